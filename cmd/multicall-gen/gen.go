@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// reserved are parameter names taken by the generated functions
+var reserved = map[string]bool{
+	"id":     true,
+	"target": true,
+	"res":    true,
+	"values": true,
+	"value":  true,
+	"out":    true,
+	"err":    true,
+	"ok":     true,
+}
+
+// generate writes Go code with a ViewCall constructor for every view and
+// pure function of contract, and a result extractor for those returning
+// values
+func generate(w io.Writer, pkg string, contract abi.ABI) error {
+	names := make([]string, 0, len(contract.Methods))
+	for name, method := range contract.Methods {
+		if method.IsConstant() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	g := &generator{
+		imports: map[string]bool{"github.com/howjmay/multicall/multicall": true},
+		structs: make(map[string]bool),
+	}
+	for _, name := range names {
+		if err := g.method(contract.Methods[name]); err != nil {
+			return fmt.Errorf("method %s: %w", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by multicall-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	var std, others []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	out.WriteString("import (\n")
+	for _, path := range std {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString("\n")
+	for _, path := range others {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+type generator struct {
+	imports map[string]bool
+	// structs are the tuple argument types already generated
+	structs map[string]bool
+	body    bytes.Buffer
+}
+
+func (g *generator) method(method abi.Method) error {
+	goName := abi.ToCamelCase(method.Name)
+
+	params := []string{"id string", "target string"}
+	args := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		name := paramName(input.Name, i)
+		goType, conversion := g.argType(input.Type, goName+abi.ToCamelCase(name))
+		params = append(params, fmt.Sprintf("%s %s", name, goType))
+		args[i] = fmt.Sprintf(conversion, name)
+	}
+
+	fmt.Fprintf(&g.body, "\n// %s builds a multicall.ViewCall of %s\n", goName, method.Sig)
+	fmt.Fprintf(&g.body, "func %s(%s) multicall.ViewCall {\n", goName, strings.Join(params, ", "))
+	fmt.Fprintf(&g.body, "\treturn multicall.NewViewCall(id, target, %q, []interface{}{%s})\n}\n", methodString(method), strings.Join(args, ", "))
+
+	switch len(method.Outputs) {
+	case 0:
+		// a call without return values only tells whether it succeeded,
+		// which CallResult.Success already does
+		return nil
+	case 1:
+		goType, zero := g.resultType(method.Outputs[0].Type)
+		fmt.Fprintf(&g.body, "\n// %sResult extracts the result of a %s call from res\n", goName, goName)
+		fmt.Fprintf(&g.body, "func %sResult(res *multicall.Result, id string) (%s, error) {\n", goName, goType)
+		g.values(1, zero)
+		g.extract("out", "values[0]", method.Outputs[0].Type, zero)
+		g.body.WriteString("\treturn out, nil\n}\n")
+		return nil
+	}
+
+	outputType := goName + "Output"
+	fmt.Fprintf(&g.body, "\n// %s holds the return values of %s\ntype %s struct {\n", outputType, method.Sig, outputType)
+	fields := make([]string, len(method.Outputs))
+	for i, output := range method.Outputs {
+		fields[i] = fieldName(output.Name, i)
+		goType, _ := g.resultType(output.Type)
+		fmt.Fprintf(&g.body, "\t%s %s\n", fields[i], goType)
+	}
+	g.body.WriteString("}\n")
+
+	fmt.Fprintf(&g.body, "\n// %sResult extracts the result of a %s call from res\n", goName, goName)
+	fmt.Fprintf(&g.body, "func %sResult(res *multicall.Result, id string) (*%s, error) {\n", goName, outputType)
+	g.values(len(method.Outputs), "nil")
+	fmt.Fprintf(&g.body, "\tresult := &%s{}\n", outputType)
+	for i, output := range method.Outputs {
+		g.body.WriteString("\t{\n")
+		g.extract("out", fmt.Sprintf("values[%d]", i), output.Type, "nil")
+		fmt.Fprintf(&g.body, "\t\tresult.%s = out\n\t}\n", fields[i])
+	}
+	g.body.WriteString("\treturn result, nil\n}\n")
+	return nil
+}
+
+// values writes statements declaring values as the decoded return values
+// of the call, returning zero with an error unless there are count of them.
+// Results of CallRaw and calls that returned no data have none.
+func (g *generator) values(count int, zero string) {
+	g.imports["fmt"] = true
+	fmt.Fprintf(&g.body, "\tvalues, err := res.Values(id)\n\tif err != nil {\n\t\treturn %s, err\n\t}\n", zero)
+	fmt.Fprintf(&g.body, "\tif len(values) != %d {\n\t\treturn %s, fmt.Errorf(\"expected %d return value(s), got %%d\", len(values))\n\t}\n", count, zero, count)
+}
+
+// argType returns the Go type of an argument and a format string
+// converting it into what multicall.NewViewCall expects. Tuples get a
+// struct type named after their Solidity struct, or hint if the ABI does
+// not name it.
+func (g *generator) argType(typ abi.Type, hint string) (string, string) {
+	if typ.T == abi.AddressTy {
+		g.imports["github.com/ethereum/go-ethereum/common"] = true
+		return "common.Address", "%s.Hex()"
+	}
+	return g.packType(typ, hint), "%s"
+}
+
+// packType returns the Go type go-ethereum packs a value of typ from,
+// generating the struct types of tuples
+func (g *generator) packType(typ abi.Type, hint string) string {
+	switch typ.T {
+	case abi.TupleTy:
+		return g.tupleType(typ, hint)
+	case abi.SliceTy, abi.ArrayTy:
+		return "[]" + g.packType(*typ.Elem, hint)
+	}
+	goType, _ := g.resultType(typ)
+	return goType
+}
+
+// tupleType writes the struct type of a tuple argument, once per name.
+// go-ethereum matches its fields to the components by name, so they are
+// named like the components the ViewCall method declares.
+func (g *generator) tupleType(typ abi.Type, hint string) string {
+	name := typ.TupleRawName
+	if name == "" {
+		name = hint
+	}
+	if g.structs[name] {
+		return name
+	}
+	g.structs[name] = true
+
+	var fields bytes.Buffer
+	for i, elem := range typ.TupleElems {
+		field := abi.ToCamelCase(typ.TupleRawNames[i])
+		fmt.Fprintf(&fields, "\t%s %s\n", field, g.packType(*elem, name+field))
+	}
+	fmt.Fprintf(&g.body, "\n// %s is a tuple%s argument\ntype %s struct {\n%s}\n", name, typ.String(), name, fields.String())
+	return name
+}
+
+// resultType returns the Go type a decoded value of typ is extracted into
+// and its zero value
+func (g *generator) resultType(typ abi.Type) (string, string) {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if typ.Size > 64 || !standardSize(typ.Size) {
+			g.imports["math/big"] = true
+			return "*big.Int", "nil"
+		}
+		prefix := "int"
+		if typ.T == abi.UintTy {
+			prefix = "uint"
+		}
+		return fmt.Sprintf("%s%d", prefix, typ.Size), "0"
+	case abi.BoolTy:
+		return "bool", "false"
+	case abi.StringTy:
+		return "string", `""`
+	case abi.AddressTy:
+		g.imports["github.com/ethereum/go-ethereum/common"] = true
+		return "common.Address", "common.Address{}"
+	case abi.BytesTy:
+		return "[]byte", "nil"
+	case abi.FixedBytesTy:
+		return fmt.Sprintf("[%d]byte", typ.Size), fmt.Sprintf("[%d]byte{}", typ.Size)
+	case abi.SliceTy, abi.ArrayTy:
+		elemType, _ := g.resultType(*typ.Elem)
+		return "[]" + elemType, "nil"
+	case abi.TupleTy:
+		return "map[string]interface{}", "nil"
+	}
+	return "interface{}", "nil"
+}
+
+// extract writes statements declaring dst as src converted to the result
+// type of typ, returning zero with an error on a type mismatch
+func (g *generator) extract(dst, src string, typ abi.Type, zero string) {
+	goType, _ := g.resultType(typ)
+	switch {
+	case goType == "*big.Int":
+		fmt.Fprintf(&g.body, "\t%s, err := multicall.AsBigInt(%s)\n\tif err != nil {\n\t\treturn %s, err\n\t}\n", dst, src, zero)
+	case typ.T == abi.SliceTy || typ.T == abi.ArrayTy:
+		g.imports["fmt"] = true
+		// nested arrays get their own loop variables
+		depth := strings.Count(dst, "Elem")
+		list, index, item, elem := dst+"List", fmt.Sprintf("i%d", depth), fmt.Sprintf("item%d", depth), dst+"Elem"
+		fmt.Fprintf(&g.body, "\t%s, ok := %s.([]interface{})\n", list, src)
+		fmt.Fprintf(&g.body, "\tif !ok {\n\t\treturn %s, fmt.Errorf(\"expected %s, got %%T\", %s)\n\t}\n", zero, typ.String(), src)
+		fmt.Fprintf(&g.body, "\t%s := make(%s, len(%s))\n", dst, goType, list)
+		fmt.Fprintf(&g.body, "\tfor %s, %s := range %s {\n", index, item, list)
+		g.extract(elem, item, *typ.Elem, zero)
+		fmt.Fprintf(&g.body, "\t%s[%s] = %s\n\t}\n", dst, index, elem)
+	default:
+		g.imports["fmt"] = true
+		fmt.Fprintf(&g.body, "\t%s, ok := %s.(%s)\n", dst, src, goType)
+		fmt.Fprintf(&g.body, "\tif !ok {\n\t\treturn %s, fmt.Errorf(\"expected %s, got %%T\", %s)\n\t}\n", zero, typ.String(), src)
+	}
+}
+
+// methodString renders method in the name(types)(types) form, keeping
+// return value and tuple component names so results are also exposed in
+// CallResult.Named
+func methodString(method abi.Method) string {
+	inputs := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		// tuple component names tell go-ethereum which struct fields to
+		// pack
+		inputs[i] = namedType(input.Type, "")
+	}
+	outputs := make([]string, len(method.Outputs))
+	for i, output := range method.Outputs {
+		outputs[i] = namedType(output.Type, output.Name)
+	}
+	if len(outputs) == 0 {
+		return fmt.Sprintf("%s(%s)", method.RawName, strings.Join(inputs, ","))
+	}
+	return fmt.Sprintf("%s(%s)(%s)", method.RawName, strings.Join(inputs, ","), strings.Join(outputs, ","))
+}
+
+func namedType(typ abi.Type, name string) string {
+	rendered := typ.String()
+	if typ.T == abi.TupleTy || ((typ.T == abi.SliceTy || typ.T == abi.ArrayTy) && typ.Elem.T == abi.TupleTy) {
+		rendered = tupleString(typ)
+	}
+	if name != "" {
+		rendered += " " + name
+	}
+	return rendered
+}
+
+func tupleString(typ abi.Type) string {
+	if typ.T != abi.TupleTy {
+		return strings.Replace(typ.String(), typ.Elem.String(), tupleString(*typ.Elem), 1)
+	}
+	components := make([]string, len(typ.TupleElems))
+	for i, elem := range typ.TupleElems {
+		components[i] = namedType(*elem, typ.TupleRawNames[i])
+	}
+	return "(" + strings.Join(components, ",") + ")"
+}
+
+func standardSize(size int) bool {
+	return size == 8 || size == 16 || size == 32 || size == 64
+}
+
+func paramName(name string, index int) string {
+	if strings.Trim(name, "_") == "" {
+		return fmt.Sprintf("arg%d", index)
+	}
+	name = abi.ToCamelCase(name)
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.IsKeyword(name) || reserved[name] {
+		name += "Arg"
+	}
+	return name
+}
+
+func fieldName(name string, index int) string {
+	if strings.Trim(name, "_") == "" {
+		return fmt.Sprintf("Ret%d", index)
+	}
+	return abi.ToCamelCase(name)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/cmd/multicall-gen/testdata/pair"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/multicall"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	f, err := os.Open("testdata/pair.json")
+	require.NoError(t, err)
+	defer f.Close()
+	contract, err := abi.JSON(f)
+	require.NoError(t, err)
+
+	var actual bytes.Buffer
+	require.NoError(t, generate(&actual, "pair", contract))
+
+	// the golden file is a package of its own so that the generated code
+	// is also compiled and exercised by TestGeneratedBindings
+	fn := "testdata/pair/pair.go"
+	if *update {
+		require.NoError(t, os.WriteFile(fn, actual.Bytes(), 0644))
+	}
+	expected, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), actual.String())
+}
+
+func TestGeneratedBindings(t *testing.T) {
+	target := "0x0000000000000000000000000000000000000001"
+	calls := multicall.ViewCalls{
+		pair.BalanceOf("balance", target, common.HexToAddress("0x1234")),
+		pair.GetBin("bin", target, 7, -2),
+		pair.GetHolders("holders", target, []*big.Int{big.NewInt(1)}),
+		pair.GetPosition("position", target, big.NewInt(3)),
+		pair.Ping("ping", target),
+	}
+	for _, call := range calls {
+		assert.NoError(t, call.Validate())
+	}
+
+	mc, err := multicall.New(nil)
+	require.NoError(t, err)
+	explanation, err := mc.Explain(calls, ethrpc.LatestBlock)
+	require.NoError(t, err)
+	bin := explanation.Calls[1]
+	assert.Equal(t, "getBin(uint8,int16)", bin.Signature)
+	assert.Equal(t, "0x"+
+		"0000000000000000000000000000000000000000000000000000000000000007"+
+		"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe", bin.Arguments)
+
+	// tuple arguments are packed from the generated structs
+	tokenIn, tokenOut := common.HexToAddress("0xaa"), common.HexToAddress("0xbb")
+	hops := []pair.QuoteRouteHops{{Pool: tokenIn, ExactIn: true, Range: pair.QuoteRouteHopsRange{Lower: big.NewInt(-10), Upper: big.NewInt(10)}}}
+	tuples := multicall.ViewCalls{
+		pair.Quote("quote", target, pair.RouterQuoteParams{TokenIn: tokenIn, TokenOut: tokenOut, Fee: big.NewInt(3000), Amount: big.NewInt(1)}),
+		pair.QuoteRoute("route", target, hops, big.NewInt(5)),
+	}
+	explanation, err = mc.Explain(tuples, ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, "quote((address,address,uint24,uint256))", explanation.Calls[0].Signature)
+	assert.Equal(t, "0x"+
+		"00000000000000000000000000000000000000000000000000000000000000aa"+
+		"00000000000000000000000000000000000000000000000000000000000000bb"+
+		"0000000000000000000000000000000000000000000000000000000000000bb8"+
+		"0000000000000000000000000000000000000000000000000000000000000001", explanation.Calls[0].Arguments)
+
+	f, err := os.Open("testdata/pair.json")
+	require.NoError(t, err)
+	defer f.Close()
+	contract, err := abi.JSON(f)
+	require.NoError(t, err)
+	expected, err := contract.Methods["quoteRoute"].Inputs.Pack(hops, big.NewInt(5))
+	require.NoError(t, err)
+	assert.Equal(t, "quoteRoute((address,bool,(int24,int24))[],uint256)", explanation.Calls[1].Signature)
+	assert.Equal(t, "0x"+hex.EncodeToString(expected), explanation.Calls[1].Arguments)
+
+	// results of CallRaw and calls without return data have no values
+	res := &multicall.Result{Calls: map[string]multicall.CallResult{
+		"decimals": {Success: true, Raw: []byte{}},
+		"reserves": {Success: true, Raw: []byte{}},
+	}}
+	_, err = pair.DecimalsResult(res, "decimals")
+	assert.Error(t, err)
+	_, err = pair.GetReservesResult(res, "reserves")
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// Command multicall-gen generates typed multicall bindings from a contract
+// ABI. For every view and pure function it emits a constructor returning a
+// multicall.ViewCall and, unless the function returns nothing, an extractor
+// reading the typed result back from a multicall.Result:
+//
+//	multicall-gen -abi erc20.json -pkg erc20 -out erc20/erc20.go
+//
+//	calls := multicall.ViewCalls{erc20.BalanceOf("dai", dai, owner)}
+//	res, err := mc.Call(calls, ethrpc.LatestBlock)
+//	balance, err := erc20.BalanceOfResult(res, "dai")
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func main() {
+	abiPath := flag.String("abi", "", "path to the contract ABI JSON, - for stdin")
+	pkg := flag.String("pkg", "", "package name of the generated code")
+	out := flag.String("out", "", "output file, stdout if empty")
+	flag.Parse()
+
+	if *abiPath == "" || *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*abiPath, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "multicall-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(abiPath, pkg, out string) error {
+	var input io.Reader = os.Stdin
+	if abiPath != "-" {
+		f, err := os.Open(abiPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	contract, err := abi.JSON(input)
+	if err != nil {
+		return fmt.Errorf("parsing ABI: %w", err)
+	}
+
+	var output io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		output = f
+	}
+	return generate(output, pkg, contract)
+}
@@ -0,0 +1,296 @@
+// Code generated by multicall-gen. DO NOT EDIT.
+
+package pair
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/multicall"
+)
+
+// BalanceOf builds a multicall.ViewCall of balanceOf(address)
+func BalanceOf(id string, target string, owner common.Address) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "balanceOf(address)(uint256)", []interface{}{owner.Hex()})
+}
+
+// BalanceOfResult extracts the result of a BalanceOf call from res
+func BalanceOfResult(res *multicall.Result, id string) (*big.Int, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, err := multicall.AsBigInt(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Decimals builds a multicall.ViewCall of decimals()
+func Decimals(id string, target string) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "decimals()(uint8)", []interface{}{})
+}
+
+// DecimalsResult extracts the result of a Decimals call from res
+func DecimalsResult(res *multicall.Result, id string) (uint8, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 1 {
+		return 0, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, ok := values[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("expected uint8, got %T", values[0])
+	}
+	return out, nil
+}
+
+// GetBin builds a multicall.ViewCall of getBin(uint8,int16)
+func GetBin(id string, target string, index uint8, offset int16) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "getBin(uint8,int16)(uint256)", []interface{}{index, offset})
+}
+
+// GetBinResult extracts the result of a GetBin call from res
+func GetBinResult(res *multicall.Result, id string) (*big.Int, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, err := multicall.AsBigInt(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetHolders builds a multicall.ViewCall of getHolders(uint256[])
+func GetHolders(id string, target string, ids []*big.Int) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "getHolders(uint256[])(address[] holders)", []interface{}{ids})
+}
+
+// GetHoldersResult extracts the result of a GetHolders call from res
+func GetHoldersResult(res *multicall.Result, id string) ([]common.Address, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	outList, ok := values[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected address[], got %T", values[0])
+	}
+	out := make([]common.Address, len(outList))
+	for i0, item0 := range outList {
+		outElem, ok := item0.(common.Address)
+		if !ok {
+			return nil, fmt.Errorf("expected address, got %T", item0)
+		}
+		out[i0] = outElem
+	}
+	return out, nil
+}
+
+// GetMatrix builds a multicall.ViewCall of getMatrix()
+func GetMatrix(id string, target string) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "getMatrix()(uint256[][])", []interface{}{})
+}
+
+// GetMatrixResult extracts the result of a GetMatrix call from res
+func GetMatrixResult(res *multicall.Result, id string) ([][]*big.Int, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	outList, ok := values[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected uint256[][], got %T", values[0])
+	}
+	out := make([][]*big.Int, len(outList))
+	for i0, item0 := range outList {
+		outElemList, ok := item0.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected uint256[], got %T", item0)
+		}
+		outElem := make([]*big.Int, len(outElemList))
+		for i1, item1 := range outElemList {
+			outElemElem, err := multicall.AsBigInt(item1)
+			if err != nil {
+				return nil, err
+			}
+			outElem[i1] = outElemElem
+		}
+		out[i0] = outElem
+	}
+	return out, nil
+}
+
+// GetPosition builds a multicall.ViewCall of getPosition(uint256)
+func GetPosition(id string, target string, idArg *big.Int) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "getPosition(uint256)((address owner,uint128 liquidity) position)", []interface{}{idArg})
+}
+
+// GetPositionResult extracts the result of a GetPosition call from res
+func GetPositionResult(res *multicall.Result, id string) (map[string]interface{}, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, ok := values[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected (address,uint128), got %T", values[0])
+	}
+	return out, nil
+}
+
+// GetReserves builds a multicall.ViewCall of getReserves()
+func GetReserves(id string, target string) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "getReserves()(uint112 _reserve0,uint112 _reserve1,uint32 _blockTimestampLast)", []interface{}{})
+}
+
+// GetReservesOutput holds the return values of getReserves()
+type GetReservesOutput struct {
+	Reserve0           *big.Int
+	Reserve1           *big.Int
+	BlockTimestampLast uint32
+}
+
+// GetReservesResult extracts the result of a GetReserves call from res
+func GetReservesResult(res *multicall.Result, id string) (*GetReservesOutput, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("expected 3 return value(s), got %d", len(values))
+	}
+	result := &GetReservesOutput{}
+	{
+		out, err := multicall.AsBigInt(values[0])
+		if err != nil {
+			return nil, err
+		}
+		result.Reserve0 = out
+	}
+	{
+		out, err := multicall.AsBigInt(values[1])
+		if err != nil {
+			return nil, err
+		}
+		result.Reserve1 = out
+	}
+	{
+		out, ok := values[2].(uint32)
+		if !ok {
+			return nil, fmt.Errorf("expected uint32, got %T", values[2])
+		}
+		result.BlockTimestampLast = out
+	}
+	return result, nil
+}
+
+// Ping builds a multicall.ViewCall of ping()
+func Ping(id string, target string) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "ping()", []interface{}{})
+}
+
+// RouterQuoteParams is a tuple(address,address,uint24,uint256) argument
+type RouterQuoteParams struct {
+	TokenIn  common.Address
+	TokenOut common.Address
+	Fee      *big.Int
+	Amount   *big.Int
+}
+
+// Quote builds a multicall.ViewCall of quote((address,address,uint24,uint256))
+func Quote(id string, target string, params RouterQuoteParams) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "quote((address tokenIn,address tokenOut,uint24 fee,uint256 amount))(uint256 amountOut)", []interface{}{params})
+}
+
+// QuoteResult extracts the result of a Quote call from res
+func QuoteResult(res *multicall.Result, id string) (*big.Int, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, err := multicall.AsBigInt(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QuoteRouteHopsRange is a tuple(int24,int24) argument
+type QuoteRouteHopsRange struct {
+	Lower *big.Int
+	Upper *big.Int
+}
+
+// QuoteRouteHops is a tuple(address,bool,(int24,int24)) argument
+type QuoteRouteHops struct {
+	Pool    common.Address
+	ExactIn bool
+	Range   QuoteRouteHopsRange
+}
+
+// QuoteRoute builds a multicall.ViewCall of quoteRoute((address,bool,(int24,int24))[],uint256)
+func QuoteRoute(id string, target string, hops []QuoteRouteHops, amount *big.Int) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "quoteRoute((address pool,bool exactIn,(int24 lower,int24 upper) range)[],uint256)(uint256)", []interface{}{hops, amount})
+}
+
+// QuoteRouteResult extracts the result of a QuoteRoute call from res
+func QuoteRouteResult(res *multicall.Result, id string) (*big.Int, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, err := multicall.AsBigInt(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Symbol builds a multicall.ViewCall of symbol()
+func Symbol(id string, target string) multicall.ViewCall {
+	return multicall.NewViewCall(id, target, "symbol()(string)", []interface{}{})
+}
+
+// SymbolResult extracts the result of a Symbol call from res
+func SymbolResult(res *multicall.Result, id string) (string, error) {
+	values, err := res.Values(id)
+	if err != nil {
+		return "", err
+	}
+	if len(values) != 1 {
+		return "", fmt.Errorf("expected 1 return value(s), got %d", len(values))
+	}
+	out, ok := values[0].(string)
+	if !ok {
+		return "", fmt.Errorf("expected string, got %T", values[0])
+	}
+	return out, nil
+}
@@ -0,0 +1,149 @@
+package ethrpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// block tags
+const (
+	BlockTag_Latest    = "latest"
+	BlockTag_Pending   = "pending"
+	BlockTag_Earliest  = "earliest"
+	BlockTag_Safe      = "safe"
+	BlockTag_Finalized = "finalized"
+)
+
+var (
+	LatestBlock    = BlockRef{tag: BlockTag_Latest}
+	PendingBlock   = BlockRef{tag: BlockTag_Pending}
+	EarliestBlock  = BlockRef{tag: BlockTag_Earliest}
+	SafeBlock      = BlockRef{tag: BlockTag_Safe}
+	FinalizedBlock = BlockRef{tag: BlockTag_Finalized}
+)
+
+// BlockRef identifies the block a request runs against: a tag, a block
+// number or an EIP-1898 block hash. The zero value refers to the latest
+// block.
+type BlockRef struct {
+	tag              string
+	number           uint64
+	hasNumber        bool
+	hash             string
+	requireCanonical bool
+}
+
+// BlockNumberRef refers to a block by number
+func BlockNumberRef(number uint64) BlockRef {
+	return BlockRef{number: number, hasNumber: true}
+}
+
+// BlockHashRef refers to a block by hash as defined in EIP-1898. With
+// requireCanonical set the node rejects the request if the block is not
+// part of the canonical chain.
+func BlockHashRef(hash string, requireCanonical bool) BlockRef {
+	return BlockRef{hash: strings.ToLower(hash), requireCanonical: requireCanonical}
+}
+
+// BlockTagRef refers to a block by tag, one of latest, pending, earliest,
+// safe or finalized
+func BlockTagRef(tag string) (BlockRef, error) {
+	switch tag {
+	case BlockTag_Latest, BlockTag_Pending, BlockTag_Earliest, BlockTag_Safe, BlockTag_Finalized:
+		return BlockRef{tag: tag}, nil
+	}
+	return BlockRef{}, fmt.Errorf("unknown block tag %q", tag)
+}
+
+// ParseBlockRef parses a block tag, a decimal or 0x prefixed hex block
+// number, or a 32 byte 0x prefixed block hash
+func ParseBlockRef(s string) (BlockRef, error) {
+	if !strings.HasPrefix(s, "0x") {
+		if number, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return BlockNumberRef(number), nil
+		}
+		return BlockTagRef(s)
+	}
+	if len(s) == 66 {
+		if _, err := hex.DecodeString(s[2:]); err != nil {
+			return BlockRef{}, fmt.Errorf("invalid block hash %q", s)
+		}
+		return BlockHashRef(s, false), nil
+	}
+	number, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return BlockRef{}, fmt.Errorf("invalid block number %q", s)
+	}
+	return BlockNumberRef(number), nil
+}
+
+// Number returns the block number, if the reference is a number
+func (b BlockRef) Number() (uint64, bool) {
+	return b.number, b.hasNumber
+}
+
+// Hash returns the block hash, if the reference is a hash
+func (b BlockRef) Hash() (string, bool) {
+	return b.hash, b.hash != ""
+}
+
+// Tag returns the block tag, if the reference is a tag
+func (b BlockRef) Tag() (string, bool) {
+	if b.hasNumber || b.hash != "" {
+		return "", false
+	}
+	if b.tag == "" {
+		return BlockTag_Latest, true
+	}
+	return b.tag, true
+}
+
+// Pin returns the reference follow-up requests should use to read the same
+// state as a request against b that ran at blockNumber. Hashes are already
+// pinned and pending blocks cannot be referenced by number, so both are
+// returned unchanged, as is b when blockNumber is 0, the number reported
+// when the block a request ran at is unknown.
+func (b BlockRef) Pin(blockNumber uint64) BlockRef {
+	if tag, ok := b.Tag(); (ok && tag == BlockTag_Pending) || b.hash != "" || blockNumber == 0 {
+		return b
+	}
+	return BlockNumberRef(blockNumber)
+}
+
+// String returns the block parameter as it appears in a request
+func (b BlockRef) String() string {
+	if b.hash != "" {
+		return b.hash
+	}
+	param, _ := b.numberOrTag()
+	return param
+}
+
+// MarshalJSON encodes the reference as a block parameter, using the
+// EIP-1898 object form for hashes
+func (b BlockRef) MarshalJSON() ([]byte, error) {
+	if b.hash != "" {
+		return json.Marshal(struct {
+			BlockHash        string `json:"blockHash"`
+			RequireCanonical bool   `json:"requireCanonical"`
+		}{b.hash, b.requireCanonical})
+	}
+	param, _ := b.numberOrTag()
+	return json.Marshal(param)
+}
+
+// numberOrTag returns the plain block parameter for methods that do not
+// support EIP-1898
+func (b BlockRef) numberOrTag() (string, error) {
+	if b.hash != "" {
+		return "", fmt.Errorf("block hash %s is not supported here, use a block number or tag", b.hash)
+	}
+	if b.hasNumber {
+		return fmt.Sprintf("0x%x", b.number), nil
+	}
+	tag, _ := b.Tag()
+	return tag, nil
+}
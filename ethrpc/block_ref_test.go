@@ -0,0 +1,49 @@
+package ethrpc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockRef(t *testing.T) {
+	hash := "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"
+	var tests = map[string]struct {
+		input    string
+		expected string
+	}{
+		"zero value": {"", `"latest"`},
+		"tag":        {"finalized", `"finalized"`},
+		"decimal":    {"3000000", `"0x2dc6c0"`},
+		"hex":        {"0x2dc6c0", `"0x2dc6c0"`},
+		"hash":       {hash, `{"blockHash":"` + hash + `","requireCanonical":false}`},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			var ref ethrpc.BlockRef
+			if tt.input != "" {
+				var err error
+				ref, err = ethrpc.ParseBlockRef(tt.input)
+				require.NoError(t, err)
+			}
+			actual, err := json.Marshal(ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(actual))
+		})
+	}
+
+	_, err := ethrpc.ParseBlockRef("lastest")
+	assert.Error(t, err)
+
+	canonical, err := json.Marshal(ethrpc.BlockHashRef(hash, true))
+	require.NoError(t, err)
+	assert.Equal(t, `{"blockHash":"`+hash+`","requireCanonical":true}`, string(canonical))
+
+	assert.Equal(t, ethrpc.BlockNumberRef(10), ethrpc.LatestBlock.Pin(10))
+	assert.Equal(t, ethrpc.PendingBlock, ethrpc.PendingBlock.Pin(10))
+	assert.Equal(t, ethrpc.LatestBlock, ethrpc.LatestBlock.Pin(0))
+}
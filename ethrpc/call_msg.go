@@ -0,0 +1,73 @@
+package ethrpc
+
+// CallMsg is the transaction call object of eth_call. Quantities are hex
+// encoded and empty fields are left out of the request.
+type CallMsg struct {
+	From                 string        `json:"from,omitempty"`
+	To                   string        `json:"to"`
+	Gas                  string        `json:"gas,omitempty"`
+	GasPrice             string        `json:"gasPrice,omitempty"`
+	MaxFeePerGas         string        `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string        `json:"maxPriorityFeePerGas,omitempty"`
+	Value                string        `json:"value,omitempty"`
+	Data                 string        `json:"data,omitempty"`
+	AccessList           []AccessTuple `json:"accessList,omitempty"`
+}
+
+// AccessTuple is an EIP-2930 access list entry
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// AccountOverride replaces parts of an account for a single eth_call.
+// State replaces the whole storage, StateDiff only the given slots.
+type AccountOverride struct {
+	Balance   string            `json:"balance,omitempty"`
+	Nonce     string            `json:"nonce,omitempty"`
+	Code      string            `json:"code,omitempty"`
+	State     map[string]string `json:"state,omitempty"`
+	StateDiff map[string]string `json:"stateDiff,omitempty"`
+}
+
+// StateOverride is the state override set of eth_call, keyed by address
+type StateOverride map[string]AccountOverride
+
+// BlockOverrides replaces fields of the block an eth_call runs in, as
+// supported by geth
+type BlockOverrides struct {
+	Number     string `json:"number,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Time       string `json:"time,omitempty"`
+	GasLimit   string `json:"gasLimit,omitempty"`
+	Coinbase   string `json:"coinbase,omitempty"`
+	Random     string `json:"random,omitempty"`
+	BaseFee    string `json:"baseFee,omitempty"`
+}
+
+// CallParams returns the eth_call parameters for msg at block, adding the
+// override sets only when given
+func CallParams(msg CallMsg, block BlockRef, state StateOverride, blockOverrides *BlockOverrides) []interface{} {
+	switch {
+	case blockOverrides != nil:
+		if state == nil {
+			state = StateOverride{}
+		}
+		return []interface{}{msg, block, state, blockOverrides}
+	case state != nil:
+		return []interface{}{msg, block, state}
+	}
+	return []interface{}{msg, block}
+}
+
+// TraceConfig configures debug_traceCall. Tracer selects a built-in tracer
+// such as "callTracer"; the default struct logger is used when it is empty.
+type TraceConfig struct {
+	Tracer         string                 `json:"tracer,omitempty"`
+	TracerConfig   map[string]interface{} `json:"tracerConfig,omitempty"`
+	DisableStorage bool                   `json:"disableStorage,omitempty"`
+	DisableStack   bool                   `json:"disableStack,omitempty"`
+	EnableMemory   bool                   `json:"enableMemory,omitempty"`
+	StateOverrides StateOverride          `json:"stateOverrides,omitempty"`
+	BlockOverrides *BlockOverrides        `json:"blockOverrides,omitempty"`
+}
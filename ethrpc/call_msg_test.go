@@ -0,0 +1,66 @@
+package ethrpc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/ethrpc/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProvider records the last request and answers it with result
+type recordingProvider struct {
+	provider.Interface
+	method string
+	params []interface{}
+	result string
+}
+
+func (p *recordingProvider) Call(result interface{}, method string, params ...interface{}) error {
+	p.method = method
+	p.params = params
+	*(*result.(*interface{})).(*string) = p.result
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	p := &recordingProvider{result: "0x01"}
+	eth, err := ethrpc.New(p)
+	require.NoError(t, err)
+
+	msg := ethrpc.CallMsg{
+		From: "0x0000000000000000000000000000000000001234",
+		To:   "0x0000000000000000000000000000000000000001",
+		Data: "0x18160ddd",
+	}
+	result, err := eth.Call(msg, ethrpc.BlockNumberRef(16))
+	require.NoError(t, err)
+	assert.Equal(t, "0x01", result)
+	assert.Equal(t, ethrpc.ETH_Call, p.method)
+
+	encoded, err := json.Marshal(p.params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"from":"0x0000000000000000000000000000000000001234","to":"0x0000000000000000000000000000000000000001","data":"0x18160ddd"},"0x10"]`, string(encoded))
+}
+
+func TestCallWithOverrides(t *testing.T) {
+	p := &recordingProvider{result: "0x"}
+	eth, err := ethrpc.New(p)
+	require.NoError(t, err)
+
+	msg := ethrpc.CallMsg{To: "0x0000000000000000000000000000000000000001", Data: "0x18160ddd"}
+	state := ethrpc.StateOverride{"0x0000000000000000000000000000000000000001": {Balance: "0x1"}}
+	_, err = eth.CallWithOverrides(msg, ethrpc.LatestBlock, state, nil)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(p.params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"to":"0x0000000000000000000000000000000000000001","data":"0x18160ddd"},"latest",{"0x0000000000000000000000000000000000000001":{"balance":"0x1"}}]`, string(encoded))
+
+	_, err = eth.CallWithOverrides(msg, ethrpc.LatestBlock, nil, &ethrpc.BlockOverrides{Number: "0x10", BaseFee: "0x0"})
+	require.NoError(t, err)
+	encoded, err = json.Marshal(p.params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"to":"0x0000000000000000000000000000000000000001","data":"0x18160ddd"},"latest",{},{"number":"0x10","baseFee":"0x0"}]`, string(encoded))
+}
@@ -19,7 +19,9 @@ const (
 	ETH_BlockNumber                      = "eth_blockNumber"
 	ETH_Call                             = "eth_call"
 	ETH_GetBalance                       = "eth_getBalance"
+	ETH_GetBlockByHash                   = "eth_getBlockByHash"
 	ETH_GetBlockByNumber                 = "eth_getBlockByNumber"
+	ETH_GetBlockTransactionCountByHash   = "eth_getBlockTransactionCountByHash"
 	ETH_GetBlockTransactionCountByNumber = "eth_getBlockTransactionCountByNumber"
 	ETH_GetCode                          = "eth_getCode"
 	ETH_GetFilterChanges                 = "eth_getFilterChanges"
@@ -32,8 +34,12 @@ const (
 
 	// trace
 	Trace_Block                   = "trace_block"
+	Trace_Call                    = "trace_call"
 	Trace_ReplayBlockTransactions = "trace_replayBlockTransactions"
 
+	// debug
+	Debug_TraceCall = "debug_traceCall"
+
 	// eth pubsub
 	ETH_NewHeads               = "newHeads"
 	ETH_NewPendingTransactions = "newPendingTransactions"
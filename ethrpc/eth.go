@@ -50,14 +50,32 @@ func (e *ETH) GetLatestBlock() (b types.Block, err error) {
 }
 
 // GetBlockByNumber gets specified block with full transaction array
-func (e *ETH) GetBlockByNumber(number string) (b types.Block, err error) {
-	err = e.SendRequest(&b, ETH_GetBlockByNumber, number, true)
+func (e *ETH) GetBlockByNumber(block BlockRef) (b types.Block, err error) {
+	if hash, ok := block.Hash(); ok {
+		err = e.SendRequest(&b, ETH_GetBlockByHash, hash, true)
+		return
+	}
+	err = e.SendRequest(&b, ETH_GetBlockByNumber, block, true)
+	return
+}
+
+// GetBlockHeader gets specified block without transactions
+func (e *ETH) GetBlockHeader(block BlockRef) (h types.BlockHeader, err error) {
+	if hash, ok := block.Hash(); ok {
+		err = e.SendRequest(&h, ETH_GetBlockByHash, hash, false)
+		return
+	}
+	err = e.SendRequest(&h, ETH_GetBlockByNumber, block, false)
 	return
 }
 
 // GetBlockTransactionCountByNumber https://wiki.parity.io/JSONRPC-eth-module.html#eth_getblocktransactioncountbynumber
-func (e *ETH) GetBlockTransactionCountByNumber(number string) (count string, err error) {
-	err = e.SendRequest(&count, ETH_GetBlockTransactionCountByNumber, number)
+func (e *ETH) GetBlockTransactionCountByNumber(block BlockRef) (count string, err error) {
+	if hash, ok := block.Hash(); ok {
+		err = e.SendRequest(&count, ETH_GetBlockTransactionCountByHash, hash)
+		return
+	}
+	err = e.SendRequest(&count, ETH_GetBlockTransactionCountByNumber, block)
 	return
 }
 
@@ -70,8 +88,11 @@ func (e *ETH) GetUncleByBlockHashAndIndex(hash string, index string) (b types.Bl
 
 // GetUncleByBlockNumberAndIndex retrieves the index-nth uncle of the
 // block with the number blockNumber
-func (e *ETH) GetUncleByBlockNumberAndIndex(blockNumber string, index string) (b types.Block, err error) {
-	err = e.SendRequest(&b, ETH_GetUncleByBlockNumberAndIndex, blockNumber, index)
+func (e *ETH) GetUncleByBlockNumberAndIndex(block BlockRef, index string) (b types.Block, err error) {
+	if hash, ok := block.Hash(); ok {
+		return e.GetUncleByBlockHashAndIndex(hash, index)
+	}
+	err = e.SendRequest(&b, ETH_GetUncleByBlockNumberAndIndex, block, index)
 	return
 }
 
@@ -169,10 +190,10 @@ func (e *ETH) GetTransactionReceipt(hash string) (r types.Receipt, err error) {
 	return
 }
 
-// GetRawBalanceAtBlock returns the balance of an address at a given blockNumber as a hex string
-func (e *ETH) GetRawBalanceAtBlock(address, blockNumber string) (string, error) {
+// GetRawBalanceAtBlock returns the balance of an address at a given block as a hex string
+func (e *ETH) GetRawBalanceAtBlock(address string, block BlockRef) (string, error) {
 	var result string
-	err := e.SendRequest(&result, ETH_GetBalance, address, blockNumber)
+	err := e.SendRequest(&result, ETH_GetBalance, address, block)
 	if err != nil {
 		return "", err
 	}
@@ -182,17 +203,17 @@ func (e *ETH) GetRawBalanceAtBlock(address, blockNumber string) (string, error)
 	return result, nil
 }
 
-// GetBalanceAtBlock returns the balance of an address at a given blockNumber as a big.Int
-func (e *ETH) GetBalanceAtBlock(address, blockNumber string) (*big.Int, error) {
-	rawBalance, err := e.GetRawBalanceAtBlock(address, blockNumber)
+// GetBalanceAtBlock returns the balance of an address at a given block as a big.Int
+func (e *ETH) GetBalanceAtBlock(address string, block BlockRef) (*big.Int, error) {
+	rawBalance, err := e.GetRawBalanceAtBlock(address, block)
 	if err != nil {
 		return nil, err
 	}
 	return utils.HexToBigInt(rawBalance)
 }
 
-// GetRawTokenBalanceAtBlock returns the token balance of an address at a given blockNumber as a hex string
-func (e *ETH) GetRawTokenBalanceAtBlock(address, token, blockNumber string) (string, error) {
+// GetRawTokenBalanceAtBlock returns the token balance of an address at a given block as a hex string
+func (e *ETH) GetRawTokenBalanceAtBlock(address, token string, block BlockRef) (string, error) {
 	var result string
 	payload := make(map[string]string)
 	payload["to"] = token
@@ -200,7 +221,7 @@ func (e *ETH) GetRawTokenBalanceAtBlock(address, token, blockNumber string) (str
 		BalanceOfFunction +
 			strings.Repeat("0", 32-len(BalanceOfFunction)+2) +
 			strings.Replace(address, "0x", "", 1)
-	err := e.SendRequest(&result, ETH_Call, payload, blockNumber)
+	err := e.SendRequest(&result, ETH_Call, payload, block)
 	if err != nil {
 		return "", err
 	}
@@ -210,9 +231,9 @@ func (e *ETH) GetRawTokenBalanceAtBlock(address, token, blockNumber string) (str
 	return result, nil
 }
 
-// GetTokenBalanceAtBlock returns the token balance of an address at a given blockNumber as a big.Int
-func (e *ETH) GetTokenBalanceAtBlock(address, token, blockNumber string) (*big.Int, error) {
-	rawBalance, err := e.GetRawTokenBalanceAtBlock(address, token, blockNumber)
+// GetTokenBalanceAtBlock returns the token balance of an address at a given block as a big.Int
+func (e *ETH) GetTokenBalanceAtBlock(address, token string, block BlockRef) (*big.Int, error) {
+	rawBalance, err := e.GetRawTokenBalanceAtBlock(address, token, block)
 	if err != nil {
 		return nil, err
 	}
@@ -264,8 +285,13 @@ func (e *ETH) GetERC20Decimals(address string) (uint8, error) {
 
 // GetCode returns the bytecode of a contract
 func (e *ETH) GetCode(a string) ([]byte, error) {
+	return e.GetCodeAtBlock(a, LatestBlock)
+}
+
+// GetCodeAtBlock returns the bytecode of a contract at a given block
+func (e *ETH) GetCodeAtBlock(a string, block BlockRef) ([]byte, error) {
 	var s string
-	err := e.SendRequest(&s, ETH_GetCode, a, "latest")
+	err := e.SendRequest(&s, ETH_GetCode, a, block)
 	if err != nil {
 		return nil, err
 	}
@@ -275,18 +301,52 @@ func (e *ETH) GetCode(a string) ([]byte, error) {
 }
 
 // Traces
-func (e *ETH) TraceBlock(blockNumber string) ([]types.Trace, error) {
+func (e *ETH) TraceBlock(block BlockRef) ([]types.Trace, error) {
+	blockNumber, err := block.numberOrTag()
+	if err != nil {
+		return nil, err
+	}
 	var traces []types.Trace
-	err := e.SendRequest(&traces, Trace_Block, blockNumber)
+	err = e.SendRequest(&traces, Trace_Block, blockNumber)
 	return traces, err
 }
 
-func (e *ETH) TraceReplayBlockTransactions(blockNumber string, traceTypes ...string) ([]types.TransactionReplay, error) {
+func (e *ETH) TraceReplayBlockTransactions(block BlockRef, traceTypes ...string) ([]types.TransactionReplay, error) {
+	blockNumber, err := block.numberOrTag()
+	if err != nil {
+		return nil, err
+	}
 	var replays []types.TransactionReplay
-	err := e.SendRequest(&replays, Trace_ReplayBlockTransactions, blockNumber, traceTypes)
+	err = e.SendRequest(&replays, Trace_ReplayBlockTransactions, blockNumber, traceTypes)
 	return replays, err
 }
 
+// TraceCall traces msg at block with trace_call, returning the requested
+// traceTypes ("trace", "vmTrace", "stateDiff")
+func (e *ETH) TraceCall(msg CallMsg, block BlockRef, traceTypes ...string) (types.TransactionReplay, error) {
+	var replay types.TransactionReplay
+	blockNumber, err := block.numberOrTag()
+	if err != nil {
+		return replay, err
+	}
+	err = e.SendRequest(&replay, Trace_Call, msg, traceTypes, blockNumber)
+	return replay, err
+}
+
+// DebugTraceCall traces msg at block with debug_traceCall and decodes the
+// tracer output into result
+func (e *ETH) DebugTraceCall(result interface{}, msg CallMsg, block BlockRef, config TraceConfig) error {
+	return e.SendRequest(result, Debug_TraceCall, msg, block, config)
+}
+
+// TraceCallFrames traces msg at block with geth's callTracer
+func (e *ETH) TraceCallFrames(msg CallMsg, block BlockRef, config TraceConfig) (types.CallFrame, error) {
+	var frame types.CallFrame
+	config.Tracer = "callTracer"
+	err := e.DebugTraceCall(&frame, msg, block, config)
+	return frame, err
+}
+
 // NewHeadsSubscription eth_subscribe to newHeads
 func (e *ETH) NewHeadsSubscription() (r chan *types.BlockHeader, err error) {
 	r = make(chan *types.BlockHeader, 100)
@@ -388,6 +448,23 @@ func (e *ETH) CallContractFunction(function string, address string, gas string)
 	return s, err
 }
 
+// Call executes msg at block without creating a transaction and returns
+// the hex encoded return data
+func (e *ETH) Call(msg CallMsg, block BlockRef) (string, error) {
+	var result string
+	err := e.SendRequest(&result, ETH_Call, msg, block)
+	return result, err
+}
+
+// CallWithOverrides executes msg at block like Call, with state replacing
+// account state and blockOverrides replacing block fields. Either may be
+// nil; block overrides are a geth extension.
+func (e *ETH) CallWithOverrides(msg CallMsg, block BlockRef, state StateOverride, blockOverrides *BlockOverrides) (string, error) {
+	var result string
+	err := e.SendRequest(&result, ETH_Call, CallParams(msg, block, state, blockOverrides)...)
+	return result, err
+}
+
 // SendRequest to server
 func (e *ETH) SendRequest(result interface{}, method string, params ...interface{}) error {
 	return e.rpc.Call(&result, method, params...)
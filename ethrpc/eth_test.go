@@ -90,7 +90,7 @@ func TestRequests(t *testing.T) {
 			blockNumber := "0x2dc6c0"
 			fn := fmt.Sprintf("../testdata/TraceBlock_%s.golden", blockNumber)
 
-			actual, err := eth.TraceBlock(blockNumber)
+			actual, err := eth.TraceBlock(ethrpc.BlockNumberRef(0x2dc6c0))
 			assert.NoError(t, err)
 
 			thelper.SaveOnUpdate(t, update, fn, actual)
@@ -103,7 +103,7 @@ func TestRequests(t *testing.T) {
 			blockNumber := "0x2dc6c0"
 			fn := fmt.Sprintf("../testdata/TraceReplayBlockTransactions_%s.golden", blockNumber)
 
-			actual, err := eth.TraceReplayBlockTransactions(blockNumber, "vmTrace", "trace", "stateDiff")
+			actual, err := eth.TraceReplayBlockTransactions(ethrpc.BlockNumberRef(0x2dc6c0), "vmTrace", "trace", "stateDiff")
 			assert.NoError(t, err)
 
 			thelper.SaveOnUpdate(t, update, fn, actual)
@@ -9,15 +9,20 @@ import (
 
 // ETHInterface defines the packages interface
 type ETHInterface interface {
+	Call(msg CallMsg, block BlockRef) (string, error)
+	CallWithOverrides(msg CallMsg, block BlockRef, state StateOverride, blockOverrides *BlockOverrides) (string, error)
+	DebugTraceCall(result interface{}, msg CallMsg, block BlockRef, config TraceConfig) error
 	CallContractFunction(function string, address string, gas string) (string, error)
 	CallContractFunctionBigInt(function string, address string) (*big.Int, error)
 	CallContractFunctionInt64(function string, address string) (int64, error)
-	GetBalanceAtBlock(address, blockNumber string) (*big.Int, error)
-	GetBlockByNumber(number string) (b types.Block, err error)
+	GetBalanceAtBlock(address string, block BlockRef) (*big.Int, error)
+	GetBlockByNumber(block BlockRef) (b types.Block, err error)
+	GetBlockHeader(block BlockRef) (h types.BlockHeader, err error)
 	GetBlockNumber() (int64, error)
-	GetBlockTransactionCountByNumber(number string) (count string, err error)
+	GetBlockTransactionCountByNumber(block BlockRef) (count string, err error)
 	GetClient() (string, error)
 	GetCode(a string) ([]byte, error)
+	GetCodeAtBlock(a string, block BlockRef) ([]byte, error)
 	GetContractName(address string) (string, error)
 	GetContractSymbol(address string) (string, error)
 	GetContractTotalSupply(address string) (*big.Int, error)
@@ -27,16 +32,18 @@ type ETHInterface interface {
 	GetPeerCount() (peers int64, err error)
 	GetPendingFilterChanges(id string) (t []string, err error)
 	GetPendingTransactions() ([]types.Transaction, error)
-	GetRawBalanceAtBlock(address, blockNumber string) (string, error)
-	GetRawTokenBalanceAtBlock(address, token, blockNumber string) (string, error)
-	GetTokenBalanceAtBlock(address, token, blockNumber string) (*big.Int, error)
+	GetRawBalanceAtBlock(address string, block BlockRef) (string, error)
+	GetRawTokenBalanceAtBlock(address, token string, block BlockRef) (string, error)
+	GetTokenBalanceAtBlock(address, token string, block BlockRef) (*big.Int, error)
 	GetTransactionByHash(hash string) (types.Transaction, error)
 	GetTransactionReceipt(hash string) (r types.Receipt, err error)
 	GetUncleByBlockHashAndIndex(hash string, index string) (b types.Block, err error)
-	GetUncleByBlockNumberAndIndex(blockNumber string, index string) (b types.Block, err error)
+	GetUncleByBlockNumberAndIndex(block BlockRef, index string) (b types.Block, err error)
 	GetVersion() (ver string, err error)
-	TraceBlock(blockNumber string) ([]types.Trace, error)
-	TraceReplayBlockTransactions(blockNumber string, traceTypes ...string) ([]types.TransactionReplay, error)
+	TraceBlock(block BlockRef) ([]types.Trace, error)
+	TraceCall(msg CallMsg, block BlockRef, traceTypes ...string) (types.TransactionReplay, error)
+	TraceCallFrames(msg CallMsg, block BlockRef, config TraceConfig) (types.CallFrame, error)
+	TraceReplayBlockTransactions(block BlockRef, traceTypes ...string) ([]types.TransactionReplay, error)
 	SendRequest(result interface{}, method string, params ...interface{}) error
 	NewBlockNumberSubscription() (r chan *int64, err error)
 	NewHeadsSubscription() (r chan *types.BlockHeader, err error)
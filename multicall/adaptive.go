@@ -0,0 +1,368 @@
+package multicall
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	rpcerrors "github.com/howjmay/multicall/errors"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/utils"
+)
+
+const (
+	// MinAdaptiveGas is the lowest gas an adaptive Multicall lowers the gas
+	// of an aggregate call to
+	MinAdaptiveGas = 1000000
+)
+
+// DefaultLimitStore is used by Adaptive when no store is given
+var DefaultLimitStore = NewLimitStore()
+
+// Limits are the chunk size and gas an adaptive Multicall learned an
+// endpoint accepts
+type Limits struct {
+	// ChunkSize is the number of calls sent in one aggregate call
+	ChunkSize int
+	// AcceptedChunkSize is the largest chunk the endpoint answered
+	AcceptedChunkSize int
+	// RejectedChunkSize is the smallest chunk the endpoint failed to
+	// answer, 0 while none failed. ChunkSize grows by bisecting between
+	// the accepted and the rejected size.
+	RejectedChunkSize int
+	// Gas is the gas sent with every aggregate call
+	Gas uint64
+	// RejectedGas is the lowest gas the endpoint rejected, 0 while none was
+	// rejected
+	RejectedGas uint64
+	// Probed is set once Gas was checked with an empty aggregate call
+	Probed bool
+}
+
+// LimitStore holds the Limits learned for every endpoint, so that the
+// Multicalls of the same endpoint share them. Limits can be saved with All
+// and restored with Set to skip learning them again.
+type LimitStore struct {
+	mu     sync.Mutex
+	limits map[string]Limits
+}
+
+func NewLimitStore() *LimitStore {
+	return &LimitStore{limits: make(map[string]Limits)}
+}
+
+// Get returns the limits learned for endpoint
+func (s *LimitStore) Get(endpoint string) (Limits, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limits, ok := s.limits[endpoint]
+	return limits, ok
+}
+
+// Set replaces the limits of endpoint
+func (s *LimitStore) Set(endpoint string, limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits[endpoint] = limits
+}
+
+// All returns the limits of every endpoint
+func (s *LimitStore) All() map[string]Limits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make(map[string]Limits, len(s.limits))
+	for endpoint, limits := range s.limits {
+		all[endpoint] = limits
+	}
+	return all
+}
+
+// update applies fn to the limits of endpoint, starting from initial when
+// the endpoint is new
+func (s *LimitStore) update(endpoint string, initial Limits, fn func(*Limits)) Limits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limits, ok := s.limits[endpoint]
+	if !ok {
+		limits = initial
+	}
+	fn(&limits)
+	s.limits[endpoint] = limits
+	return limits
+}
+
+// Limits returns the limits learned for the endpoint of an adaptive
+// Multicall, false when it is not adaptive
+func (mc multicall) Limits() (Limits, bool) {
+	if mc.config.Limits == nil {
+		return Limits{}, false
+	}
+	return mc.limits(), true
+}
+
+func (mc multicall) limits() Limits {
+	return mc.updateLimits(func(*Limits) {})
+}
+
+func (mc multicall) updateLimits(fn func(*Limits)) Limits {
+	initial := Limits{ChunkSize: mc.config.ChunkSize}
+	if initial.ChunkSize <= 0 {
+		initial.ChunkSize = DefaultChunkSize
+	}
+	if gas, err := utils.HexToBigInt(mc.config.Gas); err == nil && gas.IsUint64() {
+		initial.Gas = gas.Uint64()
+	}
+	return mc.config.Limits.update(mc.config.Endpoint, initial, fn)
+}
+
+// aggregateAdaptive sends calls in chunks of the learned chunk size with
+// the learned gas, shrinking either and retrying when the endpoint rejects
+// a chunk. Every chunk after the first is pinned to the block the first one
+// ran at, as told by the chain semantics. A gas set with CallGas is sent
+// as is, only the chunk size is learned then.
+func (mc multicall) aggregateAdaptive(calls ViewCalls, block ethrpc.BlockRef, opts []CallOption) (*AggregateResult, error) {
+	fixed := fixedGas(opts)
+	if !fixed {
+		if err := mc.probeGas(block, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &AggregateResult{}
+	pinned := false
+	for start := 0; start < len(calls); {
+		limits := mc.limits()
+		end := start + limits.ChunkSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		chunkOpts := opts
+		if !fixed {
+			chunkOpts = append([]CallOption{CallGas(limits.Gas)}, opts...)
+		}
+		decoded, err := mc.aggregateOnce(calls[start:end], block, chunkOpts)
+		if err != nil {
+			if (fixed && classifyLimitError(err) == gasLimitError) || !mc.adaptLimits(err, end-start, limits.Gas) {
+				return nil, err
+			}
+			continue
+		}
+		mc.updateLimits(func(l *Limits) {
+			l.accept(end-start, mc.config.ChunkSize)
+		})
+
+		if start == 0 {
+			result.BlockNumber, result.BlockHash = decoded.BlockNumber, decoded.BlockHash
+		}
+		result.Returns = append(result.Returns, decoded.Returns...)
+		start = end
+		if !pinned {
+			number, ok, err := mc.blockNumber(result)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				block = block.Pin(number)
+				pinned = true
+			}
+		}
+	}
+	return result, nil
+}
+
+// fixedGas reports whether opts set the gas of the aggregate call
+func fixedGas(opts []CallOption) bool {
+	config := &callConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config.msg.Gas != ""
+}
+
+// probeGas sends an empty aggregate call until the endpoint accepts its
+// gas, once per endpoint
+func (mc multicall) probeGas(block ethrpc.BlockRef, opts []CallOption) error {
+	for {
+		limits := mc.limits()
+		if limits.Probed {
+			return nil
+		}
+		_, err := mc.aggregateOnce(nil, block, append([]CallOption{CallGas(limits.Gas)}, opts...))
+		if err == nil {
+			mc.updateLimits(func(l *Limits) { l.Probed = true })
+			return nil
+		}
+		if classifyLimitError(err) != gasLimitError || !mc.adaptLimits(err, 0, limits.Gas) {
+			return err
+		}
+	}
+}
+
+// adaptLimits lowers the limit err points at and reports whether the
+// request is worth retrying
+func (mc multicall) adaptLimits(err error, size int, gas uint64) bool {
+	adapted := false
+	switch classifyLimitError(err) {
+	case gasLimitError:
+		lower := lowerGas(err.Error(), gas)
+		if lower < MinAdaptiveGas {
+			return false
+		}
+		mc.updateLimits(func(l *Limits) {
+			if l.RejectedGas == 0 || gas < l.RejectedGas {
+				l.RejectedGas = gas
+			}
+			if lower < l.Gas {
+				l.Gas = lower
+			}
+		})
+		adapted = true
+	case sizeLimitError:
+		if size <= 1 {
+			return false
+		}
+		mc.updateLimits(func(l *Limits) {
+			l.reject(size)
+		})
+		adapted = true
+	}
+	return adapted
+}
+
+// accept records that a chunk of size calls was answered and grows
+// ChunkSize when the chunk was full: twice as large while no chunk was
+// rejected, halfway to the smallest rejected chunk otherwise, at most max
+func (l *Limits) accept(size, max int) {
+	if size > l.AcceptedChunkSize {
+		l.AcceptedChunkSize = size
+	}
+	if size != l.ChunkSize {
+		return
+	}
+	next := size * 2
+	if l.RejectedChunkSize > 0 {
+		next = size + (l.RejectedChunkSize-size)/2
+	}
+	if max > 0 && next > max {
+		next = max
+	}
+	if next > l.ChunkSize {
+		l.ChunkSize = next
+	}
+}
+
+// reject records that a chunk of size calls was not answered and falls
+// back to the largest accepted chunk below it, or half of it. A rejected
+// size that was accepted before means the endpoint got stricter, so what
+// was accepted is forgotten.
+func (l *Limits) reject(size int) {
+	if size <= l.AcceptedChunkSize {
+		l.AcceptedChunkSize = 0
+	}
+	if l.RejectedChunkSize == 0 || size < l.RejectedChunkSize {
+		l.RejectedChunkSize = size
+	}
+	next := size / 2
+	if l.AcceptedChunkSize > next {
+		next = l.AcceptedChunkSize
+	}
+	if next < l.ChunkSize {
+		l.ChunkSize = next
+	}
+}
+
+type limitError int
+
+const (
+	notLimitError limitError = iota
+	// gasLimitError is a gas value above the cap of the endpoint
+	gasLimitError
+	// sizeLimitError is a request or response too large for the endpoint,
+	// or an aggregate call running out of gas or hitting the execution
+	// timeout of the node
+	sizeLimitError
+)
+
+// rateLimitCode is the EIP-1474 "limit exceeded" code, which endpoints
+// such as Infura return when too many requests were sent
+const rateLimitCode = -32005
+
+var (
+	gasLimitMessages = []string{
+		"gas cap",
+		"gas limit too high",
+		"gas too high",
+		"exceeds block gas limit",
+		"exceeds the configured cap",
+		"gas limit exceeded",
+		"gas limit is too high",
+	}
+	// sizeLimitMessages leave out timeouts of the transport, which say
+	// nothing about the batch; only the node aborting the execution of an
+	// eth_call is matched
+	sizeLimitMessages = []string{
+		"out of gas",
+		// geth's "gas required exceeds allowance" is a batch needing
+		// more gas than it was given
+		"exceeds allowance",
+		"too large",
+		"too big",
+		"response size",
+		"size exceeded",
+		"execution aborted (timeout",
+	}
+	// rateLimitMessages are errors of endpoints throttling requests, which
+	// a smaller chunk does not help against
+	rateLimitMessages = []string{
+		"rate limit",
+		"too many requests",
+		"request limit",
+		"daily request count",
+		"exceeded its compute units",
+		"capacity exceeded",
+	}
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+func classifyLimitError(err error) limitError {
+	var rpcErr *rpcerrors.RpcError
+	if errors.As(err, &rpcErr) && rpcErr.Code == rateLimitCode {
+		return notLimitError
+	}
+	message := strings.ToLower(err.Error())
+	for _, pattern := range rateLimitMessages {
+		if strings.Contains(message, pattern) {
+			return notLimitError
+		}
+	}
+	for _, pattern := range gasLimitMessages {
+		if strings.Contains(message, pattern) {
+			return gasLimitError
+		}
+	}
+	for _, pattern := range sizeLimitMessages {
+		if strings.Contains(message, pattern) {
+			return sizeLimitError
+		}
+	}
+	return notLimitError
+}
+
+// lowerGas returns the gas to retry with after gas was rejected with
+// message: the largest number in the message below gas, which usually is
+// the cap of the endpoint, or half of gas
+func lowerGas(message string, gas uint64) uint64 {
+	var lower uint64
+	for _, match := range numberPattern.FindAllString(message, -1) {
+		n, err := strconv.ParseUint(match, 10, 64)
+		if err == nil && n >= MinAdaptiveGas && n < gas && n > lower {
+			lower = n
+		}
+	}
+	if lower == 0 {
+		lower = gas / 2
+	}
+	return lower
+}
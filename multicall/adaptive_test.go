@@ -0,0 +1,148 @@
+package multicall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	rpcerrors "github.com/howjmay/multicall/errors"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedETH rejects aggregate calls above its gas cap and with more than
+// maxCalls calls
+type limitedETH struct {
+	*fakeETH
+	gasCap   uint64
+	maxCalls int
+	sizes    []int
+}
+
+func (l *limitedETH) SendRequest(result interface{}, method string, params ...interface{}) error {
+	msg := params[0].(ethrpc.CallMsg)
+	gas, err := parseGas(msg.Gas)
+	if err != nil {
+		return err
+	}
+	if gas > l.gasCap {
+		return fmt.Errorf("rpc gas cap exceeded: requested %d, cap %d", gas, l.gasCap)
+	}
+	decoded, err := DecodeAggregateCallData(common.FromHex(msg.Data), nil)
+	if err != nil {
+		return err
+	}
+	l.sizes = append(l.sizes, len(decoded.Calls))
+	if len(decoded.Calls) > l.maxCalls {
+		return errors.New("response size exceeded")
+	}
+	return l.fakeETH.SendRequest(result, method, params...)
+}
+
+func TestAdaptive(t *testing.T) {
+	store := NewLimitStore()
+	eth := &limitedETH{fakeETH: &fakeETH{blockNumber: 42, respond: echoArgument}, gasCap: 50000000, maxCalls: 40}
+	mc, err := New(eth, ChunkSize(64), Adaptive(store, "node"))
+	require.NoError(t, err)
+
+	calls := numberedCalls(300)
+	res, err := mc.Call(calls, ethrpc.LatestBlock)
+	require.NoError(t, err)
+	require.Len(t, res.Calls, 300)
+	for i, call := range calls {
+		assert.Equal(t, int64(i), res.Calls[call.id].Decoded[0].(*BigIntJSONString).ToBigInt().Int64())
+	}
+	assert.Equal(t, uint64(42), res.BlockNumber)
+	assert.Equal(t, ethrpc.BlockNumberRef(42), eth.params[1])
+
+	limits, ok := mc.Limits()
+	require.True(t, ok)
+	assert.Equal(t, Limits{
+		ChunkSize:         40,
+		AcceptedChunkSize: 40,
+		RejectedChunkSize: 41,
+		Gas:               50000000,
+		RejectedGas:       0x400000000,
+		Probed:            true,
+	}, limits)
+	stored, ok := store.Get("node")
+	require.True(t, ok)
+	assert.Equal(t, limits, stored)
+
+	// a Multicall of the same endpoint starts from the learned limits
+	eth.sizes = nil
+	other, err := New(eth, ChunkSize(64), Adaptive(store, "node"))
+	require.NoError(t, err)
+	_, err = other.Call(calls[:80], ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, []int{40, 40}, eth.sizes)
+
+	plain, err := New(eth)
+	require.NoError(t, err)
+	_, ok = plain.Limits()
+	assert.False(t, ok)
+}
+
+func TestAdaptiveErrors(t *testing.T) {
+	eth := &limitedETH{fakeETH: &fakeETH{blockNumber: 42, respond: echoArgument}, gasCap: 50000000, maxCalls: 0}
+	mc, err := New(eth, Adaptive(NewLimitStore(), "node"))
+	require.NoError(t, err)
+
+	// a single call that cannot be answered is not retried forever
+	_, err = mc.Call(numberedCalls(4), ethrpc.LatestBlock)
+	assert.True(t, strings.Contains(err.Error(), "response size exceeded"))
+
+	eth.gasCap = MinAdaptiveGas / 2
+	mc, err = New(eth, Adaptive(NewLimitStore(), "node"))
+	require.NoError(t, err)
+	_, err = mc.Call(numberedCalls(4), ethrpc.LatestBlock)
+	assert.True(t, strings.Contains(err.Error(), "gas cap"))
+}
+
+func TestAdaptiveFixedGas(t *testing.T) {
+	eth := &limitedETH{fakeETH: &fakeETH{blockNumber: 42, respond: echoArgument}, gasCap: 50000000, maxCalls: 40}
+	mc, err := New(eth, ChunkSize(64), Adaptive(NewLimitStore(), "node"))
+	require.NoError(t, err)
+
+	// the gas set by the caller is sent without probing, chunks still adapt
+	_, err = mc.Call(numberedCalls(100), ethrpc.LatestBlock, CallGas(40000000))
+	require.NoError(t, err)
+	assert.Equal(t, "0x2625a00", eth.params[0].(ethrpc.CallMsg).Gas)
+	limits, _ := mc.Limits()
+	assert.False(t, limits.Probed)
+	assert.Equal(t, 40, limits.ChunkSize)
+
+	// a gas above the cap fails rather than being lowered
+	_, err = mc.Call(numberedCalls(4), ethrpc.LatestBlock, CallGas(60000000))
+	assert.True(t, strings.Contains(err.Error(), "gas cap"))
+	limits, _ = mc.Limits()
+	assert.Zero(t, limits.RejectedGas)
+}
+
+func TestClassifyLimitError(t *testing.T) {
+	for message, expected := range map[string]limitError{
+		"err: exceeds block gas limit":                               gasLimitError,
+		"gas limit too high":                                         gasLimitError,
+		"execution aborted (timeout = 5s)":                           sizeLimitError,
+		"out of gas":                                                 sizeLimitError,
+		"gas required exceeds allowance (50000000)":                  sizeLimitError,
+		"failed to decode <html>413 Request Entity Too Large</html>": sizeLimitError,
+		"execution reverted":                                         notLimitError,
+		// rate limits and transport timeouts say nothing about the batch
+		"project ID request rate exceeded; rate limit exceeded":                       notLimitError,
+		"429 Too Many Requests":                                                       notLimitError,
+		"Post \"https://rpc.ankr.com/eth\": context deadline exceeded":                notLimitError,
+		"net/http: request canceled (Client.Timeout exceeded while awaiting headers)": notLimitError,
+		"read tcp 10.0.0.1:443: i/o timeout":                                          notLimitError,
+	} {
+		assert.Equal(t, expected, classifyLimitError(errors.New(message)), message)
+	}
+	// -32005 is a rate limit even when the message names a size
+	assert.Equal(t, notLimitError, classifyLimitError(rpcerrors.New("response size exceeded", -32005, "")))
+	assert.Equal(t, sizeLimitError, classifyLimitError(rpcerrors.New("response size exceeded", -32000, "")))
+	assert.Equal(t, uint64(50000000), lowerGas("requested 17179869184, cap 50000000", 17179869184))
+	assert.Equal(t, uint64(8589934592), lowerGas("gas too high", 17179869184))
+}
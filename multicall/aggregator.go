@@ -0,0 +1,466 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// selectors of the supported aggregate functions
+const (
+	// aggregate((address,bytes)[],bool)
+	AggregateMethod = "0x17352e13"
+	// aggregate((address,bytes)[]) of MakerDAO's original Multicall
+	MakerAggregateMethod = "0x252dba42"
+	// tryAggregate(bool,(address,bytes)[]) of Multicall2
+	TryAggregateMethod = "0xbce38bd7"
+	// blockAndAggregate((address,bytes)[]) of Multicall2
+	BlockAndAggregateMethod = "0xc3077fa9"
+	// tryBlockAndAggregate(bool,(address,bytes)[]) of Multicall2
+	TryBlockAndAggregateMethod = "0x399542e9"
+	// getBlockNumber() of Multicall2 and Multicall3
+	GetBlockNumberMethod = "0x42cbb15c"
+	// aggregate3((address,bool,bytes)[]) of Multicall3
+	Aggregate3Method = "0x82ad56cb"
+	// aggregate3Value((address,bool,uint256,bytes)[]) of Multicall3
+	Aggregate3ValueMethod = "0x174dea71"
+)
+
+// AggregateCall is a single call packed into an aggregate call
+type AggregateCall struct {
+	Target   [20]byte
+	CallData []byte
+	// Value is the wei sent with the call by Aggregate3ValueAggregator, nil
+	// for none
+	Value *big.Int
+}
+
+// AggregateReturn is the outcome of a single call in an aggregate call
+type AggregateReturn struct {
+	Success bool
+	Data    []byte
+	// NoCode is set when CheckCode found no code at the call target
+	NoCode bool
+}
+
+func (r AggregateReturn) status() CallStatus {
+	switch {
+	case r.NoCode:
+		return StatusNoCode
+	case r.Success:
+		return StatusSuccess
+	}
+	return StatusFailed
+}
+
+// AggregateResult is the decoded return value of an aggregate call.
+// BlockNumber is nil and BlockHash empty when the contract function does
+// not return them.
+type AggregateResult struct {
+	BlockNumber *big.Int
+	BlockHash   string
+	Returns     []AggregateReturn
+}
+
+// Aggregator encodes a batch of calls for a specific multicall contract
+// function and decodes what it returns. Implement it to use a contract that
+// is not covered by the aggregators in this package.
+type Aggregator interface {
+	// EncodeCalls returns the complete calldata, including the selector
+	EncodeCalls(calls []AggregateCall) ([]byte, error)
+	DecodeResults(raw []byte) (*AggregateResult, error)
+}
+
+// blockNumberReader is implemented by the aggregators whose function does
+// not return the block number. A getBlockNumber() call of the multicall
+// contract is appended to their batches instead.
+type blockNumberReader interface {
+	readsBlockNumber()
+}
+
+// valueSender is implemented by the aggregators that send the Value of
+// every call
+type valueSender interface {
+	sendsValue()
+}
+
+// getBlockNumberCall calls getBlockNumber() of the multicall contract at
+// address
+func getBlockNumberCall(address string) (AggregateCall, error) {
+	target, err := toByteArray(address)
+	if err != nil {
+		return AggregateCall{}, err
+	}
+	selector, err := hex.DecodeString(GetBlockNumberMethod[2:])
+	if err != nil {
+		return AggregateCall{}, err
+	}
+	return AggregateCall{Target: target, CallData: selector}, nil
+}
+
+// readBlockNumber strips the result of the getBlockNumber() call appended
+// last from decoded and sets decoded.BlockNumber from it
+func readBlockNumber(decoded *AggregateResult) error {
+	last := decoded.Returns[len(decoded.Returns)-1]
+	if !last.Success || len(last.Data) != 32 {
+		return fmt.Errorf("getBlockNumber() failed, the multicall contract may not support it")
+	}
+	decoded.BlockNumber = new(big.Int).SetBytes(last.Data)
+	decoded.Returns = decoded.Returns[:len(decoded.Returns)-1]
+	return nil
+}
+
+var (
+	boolType      = mustNewType("bool", nil)
+	uint256Type   = mustNewType("uint256", nil)
+	bytes32Type   = mustNewType("bytes32", nil)
+	bytesListType = mustNewType("bytes[]", nil)
+	callListType  = mustNewType("tuple[]", []abi.ArgumentMarshaling{
+		{Type: "address", Name: "Target"},
+		{Type: "bytes", Name: "CallData"},
+	})
+	call3ListType = mustNewType("tuple[]", []abi.ArgumentMarshaling{
+		{Type: "address", Name: "Target"},
+		{Type: "bool", Name: "AllowFailure"},
+		{Type: "bytes", Name: "CallData"},
+	})
+	call3ValueListType = mustNewType("tuple[]", []abi.ArgumentMarshaling{
+		{Type: "address", Name: "Target"},
+		{Type: "bool", Name: "AllowFailure"},
+		{Type: "uint256", Name: "Value"},
+		{Type: "bytes", Name: "CallData"},
+	})
+	returnListType = mustNewType("tuple[]", []abi.ArgumentMarshaling{
+		{Type: "bool", Name: "Success"},
+		{Type: "bytes", Name: "Data"},
+	})
+)
+
+func mustNewType(t string, components []abi.ArgumentMarshaling) abi.Type {
+	typ, err := abi.NewType(t, "", components)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// encodeAggregate encodes the arguments of method, bools and callLists,
+// after its selector. Batches hold thousands of calls, so they are written
+// directly rather than packed through reflection by go-ethereum.
+func encodeAggregate(method string, args ...interface{}) ([]byte, error) {
+	selector, err := hex.DecodeString(method[2:])
+	if err != nil {
+		return nil, err
+	}
+	size := len(selector) + 32*len(args)
+	for _, arg := range args {
+		if list, ok := arg.(callList); ok {
+			size += list.size()
+		}
+	}
+	out := make([]byte, 0, size)
+	out = append(out, selector...)
+	offset := 32 * len(args)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case bool:
+			out = appendBool(out, v)
+		case callList:
+			out = appendUint(out, uint64(offset))
+			offset += v.size()
+		default:
+			return nil, fmt.Errorf("cannot encode %T", arg)
+		}
+	}
+	for _, arg := range args {
+		if list, ok := arg.(callList); ok {
+			out = list.appendTo(out)
+		}
+	}
+	return out, nil
+}
+
+// callList encodes calls as a tuple[] whose tuples start with the static
+// words head appends for a call, e.g. its target, and end with its
+// calldata
+type callList struct {
+	calls []AggregateCall
+	// words is the number of words head appends
+	words int
+	head  func(out []byte, i int) []byte
+}
+
+func (l callList) size() int {
+	size := 32 + 32*len(l.calls)
+	for i := range l.calls {
+		size += l.tupleSize(i)
+	}
+	return size
+}
+
+// tupleSize is the size of the static words, the calldata offset and
+// length and the padded calldata of call i
+func (l callList) tupleSize(i int) int {
+	return 32*(l.words+2) + (len(l.calls[i].CallData)+31)/32*32
+}
+
+func (l callList) appendTo(out []byte) []byte {
+	out = appendUint(out, uint64(len(l.calls)))
+	offset := 32 * len(l.calls)
+	for i := range l.calls {
+		out = appendUint(out, uint64(offset))
+		offset += l.tupleSize(i)
+	}
+	for i, call := range l.calls {
+		out = l.head(out, i)
+		out = appendUint(out, uint64(32*(l.words+1)))
+		out = appendUint(out, uint64(len(call.CallData)))
+		out = append(out, call.CallData...)
+		if rest := len(call.CallData) % 32; rest != 0 {
+			out = append(out, zeroWord[:32-rest]...)
+		}
+	}
+	return out
+}
+
+// targetList is the (address,bytes)[] most aggregate functions take
+func targetList(calls []AggregateCall) callList {
+	return callList{calls: calls, words: 1, head: func(out []byte, i int) []byte {
+		return appendAddress(out, calls[i].Target)
+	}}
+}
+
+var zeroWord [32]byte
+
+func appendUint(out []byte, v uint64) []byte {
+	out = append(out, zeroWord[:24]...)
+	return append(out, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendBool(out []byte, v bool) []byte {
+	if v {
+		return appendUint(out, 1)
+	}
+	return appendUint(out, 0)
+}
+
+func appendBigInt(out []byte, v *big.Int) []byte {
+	out = append(out, zeroWord[:]...)
+	v.FillBytes(out[len(out)-32:])
+	return out
+}
+
+func appendAddress(out []byte, address [20]byte) []byte {
+	out = append(out, zeroWord[:12]...)
+	return append(out, address[:]...)
+}
+
+// decodeReturns converts a decoded (bool,bytes)[] into AggregateReturns
+func decodeReturns(data interface{}) []AggregateReturn {
+	returns := reflect.ValueOf(data)
+	decoded := make([]AggregateReturn, returns.Len())
+	for i := range decoded {
+		elem := returns.Index(i)
+		decoded[i] = AggregateReturn{
+			Success: elem.FieldByName("Success").Bool(),
+			Data:    elem.FieldByName("Data").Bytes(),
+		}
+	}
+	return decoded
+}
+
+type strictAggregator struct {
+	strict bool
+}
+
+// StrictAggregator calls aggregate((address,bytes)[],bool) returning
+// (uint256,(bool,bytes)[]), the contract deployed at MainnetAddress. With
+// strict set a single failing call reverts the whole batch. This is the
+// default aggregator.
+func StrictAggregator(strict bool) Aggregator {
+	return strictAggregator{strict: strict}
+}
+
+func (a strictAggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	return encodeAggregate(AggregateMethod, targetList(calls), a.strict)
+}
+
+func (a strictAggregator) DecodeResults(raw []byte) (*AggregateResult, error) {
+	args := abi.Arguments{
+		{Type: uint256Type, Name: "BlockNumber"},
+		{Type: returnListType, Name: "Returns"},
+	}
+	data, err := args.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateResult{
+		BlockNumber: data[0].(*big.Int),
+		Returns:     decodeReturns(data[1]),
+	}, nil
+}
+
+type makerAggregator struct{}
+
+// MakerAggregator calls aggregate((address,bytes)[]) returning
+// (uint256,bytes[]) of MakerDAO's original Multicall. The contract reverts
+// if any call fails, so every returned call is successful.
+func MakerAggregator() Aggregator {
+	return makerAggregator{}
+}
+
+func (makerAggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	return encodeAggregate(MakerAggregateMethod, targetList(calls))
+}
+
+func (makerAggregator) DecodeResults(raw []byte) (*AggregateResult, error) {
+	args := abi.Arguments{
+		{Type: uint256Type, Name: "blockNumber"},
+		{Type: bytesListType, Name: "returnData"},
+	}
+	data, err := args.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	returnData := data[1].([][]byte)
+	returns := make([]AggregateReturn, len(returnData))
+	for i, ret := range returnData {
+		returns[i] = AggregateReturn{Success: true, Data: ret}
+	}
+	return &AggregateResult{
+		BlockNumber: data[0].(*big.Int),
+		Returns:     returns,
+	}, nil
+}
+
+type tryAggregator struct {
+	requireSuccess bool
+}
+
+// TryAggregator calls tryAggregate(bool,(address,bytes)[]) of Multicall2.
+// The function does not return the block number, so Multicall appends a
+// call to getBlockNumber() of the contract to every batch.
+func TryAggregator(requireSuccess bool) Aggregator {
+	return tryAggregator{requireSuccess: requireSuccess}
+}
+
+func (a tryAggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	return encodeAggregate(TryAggregateMethod, a.requireSuccess, targetList(calls))
+}
+
+func (tryAggregator) readsBlockNumber() {}
+
+func (tryAggregator) DecodeResults(raw []byte) (*AggregateResult, error) {
+	data, err := abi.Arguments{{Type: returnListType, Name: "returnData"}}.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateResult{Returns: decodeReturns(data[0])}, nil
+}
+
+type blockAndAggregator struct {
+	requireSuccess bool
+	try            bool
+}
+
+// BlockAndAggregator calls blockAndAggregate((address,bytes)[]) of
+// Multicall2, which reverts if any call fails and returns the block hash
+// alongside the block number
+func BlockAndAggregator() Aggregator {
+	return blockAndAggregator{}
+}
+
+// TryBlockAndAggregator calls tryBlockAndAggregate(bool,(address,bytes)[])
+// of Multicall2
+func TryBlockAndAggregator(requireSuccess bool) Aggregator {
+	return blockAndAggregator{requireSuccess: requireSuccess, try: true}
+}
+
+func (a blockAndAggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	if !a.try {
+		return encodeAggregate(BlockAndAggregateMethod, targetList(calls))
+	}
+	return encodeAggregate(TryBlockAndAggregateMethod, a.requireSuccess, targetList(calls))
+}
+
+func (blockAndAggregator) DecodeResults(raw []byte) (*AggregateResult, error) {
+	args := abi.Arguments{
+		{Type: uint256Type, Name: "blockNumber"},
+		{Type: bytes32Type, Name: "blockHash"},
+		{Type: returnListType, Name: "returnData"},
+	}
+	data, err := args.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	blockHash := data[1].([32]byte)
+	return &AggregateResult{
+		BlockNumber: data[0].(*big.Int),
+		BlockHash:   fmt.Sprintf("0x%x", blockHash),
+		Returns:     decodeReturns(data[2]),
+	}, nil
+}
+
+type aggregate3Aggregator struct {
+	allowFailure bool
+}
+
+// Aggregate3Aggregator calls aggregate3((address,bool,bytes)[]) of
+// Multicall3, allowing every call to fail when allowFailure is set. Like
+// tryAggregate it does not return the block number, which Multicall reads
+// with getBlockNumber() instead.
+func Aggregate3Aggregator(allowFailure bool) Aggregator {
+	return aggregate3Aggregator{allowFailure: allowFailure}
+}
+
+func (a aggregate3Aggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	calls3 := callList{calls: calls, words: 2, head: func(out []byte, i int) []byte {
+		out = appendAddress(out, calls[i].Target)
+		return appendBool(out, a.allowFailure)
+	}}
+	return encodeAggregate(Aggregate3Method, calls3)
+}
+
+func (aggregate3Aggregator) readsBlockNumber() {}
+
+func (aggregate3Aggregator) DecodeResults(raw []byte) (*AggregateResult, error) {
+	data, err := abi.Arguments{{Type: returnListType, Name: "returnData"}}.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateResult{Returns: decodeReturns(data[0])}, nil
+}
+
+type aggregate3ValueAggregator struct {
+	aggregate3Aggregator
+}
+
+// Aggregate3ValueAggregator calls the payable
+// aggregate3Value((address,bool,uint256,bytes)[]) of Multicall3, sending
+// the Value of every call with it. The Value CallOption needs it. Like
+// aggregate3 it does not return the block number.
+func Aggregate3ValueAggregator(allowFailure bool) Aggregator {
+	return aggregate3ValueAggregator{aggregate3Aggregator{allowFailure: allowFailure}}
+}
+
+func (a aggregate3ValueAggregator) EncodeCalls(calls []AggregateCall) ([]byte, error) {
+	for _, call := range calls {
+		if call.Value != nil && (call.Value.Sign() < 0 || call.Value.BitLen() > 256) {
+			return nil, fmt.Errorf("invalid call value %s", call.Value)
+		}
+	}
+	calls3 := callList{calls: calls, words: 3, head: func(out []byte, i int) []byte {
+		out = appendAddress(out, calls[i].Target)
+		out = appendBool(out, a.allowFailure)
+		if calls[i].Value == nil {
+			return appendUint(out, 0)
+		}
+		return appendBigInt(out, calls[i].Value)
+	}}
+	return encodeAggregate(Aggregate3ValueMethod, calls3)
+}
+
+func (aggregate3ValueAggregator) sendsValue() {}
@@ -0,0 +1,141 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregators(t *testing.T) {
+	calls := []AggregateCall{
+		{Target: [20]byte{1}, CallData: []byte{0x95, 0xd8, 0x9b, 0x41}},
+		{Target: [20]byte{2}, CallData: append([]byte{0x70, 0xa0, 0x82, 0x31}, common.LeftPadBytes([]byte{0x12, 0x34}, 33)...)},
+		{Target: [20]byte{3}, CallData: []byte{}},
+	}
+	returns := []AggregateReturn{{Success: true, Data: []byte{1, 2}}}
+	blockHash := [32]byte{0xab}
+
+	var tests = map[string]struct {
+		aggregator Aggregator
+		selector   string
+		output     func() ([]byte, error)
+		expected   AggregateResult
+	}{
+		"StrictAggregator": {
+			aggregator: StrictAggregator(false),
+			selector:   AggregateMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: uint256Type}, {Type: returnListType}}.Pack(big.NewInt(7), returns)
+			},
+			expected: AggregateResult{BlockNumber: big.NewInt(7), Returns: returns},
+		},
+		"MakerAggregator": {
+			aggregator: MakerAggregator(),
+			selector:   MakerAggregateMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: uint256Type}, {Type: bytesListType}}.Pack(big.NewInt(7), [][]byte{{1, 2}})
+			},
+			expected: AggregateResult{BlockNumber: big.NewInt(7), Returns: returns},
+		},
+		"TryAggregator": {
+			aggregator: TryAggregator(false),
+			selector:   TryAggregateMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: returnListType}}.Pack(returns)
+			},
+			expected: AggregateResult{Returns: returns},
+		},
+		"BlockAndAggregator": {
+			aggregator: BlockAndAggregator(),
+			selector:   BlockAndAggregateMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: uint256Type}, {Type: bytes32Type}, {Type: returnListType}}.Pack(big.NewInt(7), blockHash, returns)
+			},
+			expected: AggregateResult{BlockNumber: big.NewInt(7), BlockHash: "0xab" + "00000000000000000000000000000000000000000000000000000000000000", Returns: returns},
+		},
+		"TryBlockAndAggregator": {
+			aggregator: TryBlockAndAggregator(true),
+			selector:   TryBlockAndAggregateMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: uint256Type}, {Type: bytes32Type}, {Type: returnListType}}.Pack(big.NewInt(7), blockHash, returns)
+			},
+			expected: AggregateResult{BlockNumber: big.NewInt(7), BlockHash: "0xab" + "00000000000000000000000000000000000000000000000000000000000000", Returns: returns},
+		},
+		"Aggregate3ValueAggregator": {
+			aggregator: Aggregate3ValueAggregator(true),
+			selector:   Aggregate3ValueMethod,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: returnListType}}.Pack(returns)
+			},
+			expected: AggregateResult{Returns: returns},
+		},
+		"Aggregate3Aggregator": {
+			aggregator: Aggregate3Aggregator(true),
+			selector:   Aggregate3Method,
+			output: func() ([]byte, error) {
+				return abi.Arguments{{Type: returnListType}}.Pack(returns)
+			},
+			expected: AggregateResult{Returns: returns},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			callData, err := tt.aggregator.EncodeCalls(calls)
+			require.NoError(t, err)
+			assert.Equal(t, tt.selector, "0x"+hex.EncodeToString(callData[:4]))
+			// the calldata is written directly, go-ethereum packs the same
+			decoded, err := DecodeAggregateCallData(callData, nil)
+			require.NoError(t, err)
+			require.Len(t, decoded.Calls, len(calls))
+			for i, call := range decoded.Calls {
+				assert.True(t, strings.EqualFold(common.Address(calls[i].Target).Hex(), call.Target), call.Target)
+				assert.Equal(t, calls[i].CallData, call.CallData)
+			}
+			values, err := aggregateFunctions[tt.selector].inputs.Unpack(callData[4:])
+			require.NoError(t, err)
+			packed, err := aggregateFunctions[tt.selector].inputs.Pack(values...)
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(packed), hex.EncodeToString(callData[4:]))
+
+			output, err := tt.output()
+			require.NoError(t, err)
+			actual, err := tt.aggregator.DecodeResults(output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *actual)
+		})
+	}
+}
+
+func TestGetBlockNumberCall(t *testing.T) {
+	for _, aggregator := range []Aggregator{TryAggregator(false), Aggregate3Aggregator(true)} {
+		mc, err := New(nil, ContractAddress(Multicall3Address), WithAggregator(aggregator))
+		require.NoError(t, err)
+		explanation, err := mc.Explain(numberedCalls(2), ethrpc.LatestBlock)
+		require.NoError(t, err)
+
+		input, err := hex.DecodeString(explanation.CallData[2:])
+		require.NoError(t, err)
+		decoded, err := DecodeAggregateCallData(input, nil)
+		require.NoError(t, err)
+		require.Len(t, decoded.Calls, 3)
+		last := decoded.Calls[2]
+		assert.Equal(t, common.HexToAddress(Multicall3Address).Hex(), last.Target)
+		assert.Equal(t, GetBlockNumberMethod, "0x"+hex.EncodeToString(last.CallData))
+	}
+
+	// aggregators returning the block number need no extra call
+	mc, err := New(nil)
+	require.NoError(t, err)
+	request, err := mc.(*multicall).prepare(numberedCalls(2), nil)
+	require.NoError(t, err)
+	assert.Len(t, request.calls, 2)
+	assert.False(t, request.blockNumber)
+}
@@ -0,0 +1,191 @@
+package multicall
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+const (
+	// DefaultBatchWindow is how long a Batcher waits for more calls after
+	// the first call of a batch
+	DefaultBatchWindow = 10 * time.Millisecond
+)
+
+// ErrBatcherClosed is returned for calls loaded after Close
+var ErrBatcherClosed = errors.New("batcher closed")
+
+// Future is the pending result of a call loaded into a Batcher
+type Future struct {
+	id     string
+	done   chan struct{}
+	result CallResult
+	err    error
+}
+
+// Done is closed once the result is available
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the batch holding the call has been sent. The error is
+// set when the aggregate call itself failed; a failed or undecodable call
+// is reported in the CallResult like in Result.Calls.
+func (f *Future) Wait() (CallResult, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Values waits for the result and returns the decoded return values, like
+// Result.Values
+func (f *Future) Values() ([]interface{}, error) {
+	result, err := f.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result.values(f.id)
+}
+
+func (f *Future) resolve(result CallResult, err error) {
+	f.result = result
+	f.err = err
+	close(f.done)
+}
+
+type batchEntry struct {
+	call   ViewCall
+	future *Future
+}
+
+type BatcherOption func(*Batcher)
+
+// MaxBatchSize flushes a batch as soon as it holds size calls,
+// DefaultChunkSize by default
+func MaxBatchSize(size int) BatcherOption {
+	return func(b *Batcher) {
+		b.maxSize = size
+	}
+}
+
+// BatchWindow sets how long a batch collects calls before it is flushed,
+// DefaultBatchWindow by default
+func BatchWindow(window time.Duration) BatcherOption {
+	return func(b *Batcher) {
+		b.window = window
+	}
+}
+
+// BatchBlock sets the block every batch runs at, LatestBlock by default
+func BatchBlock(block ethrpc.BlockRef) BatcherOption {
+	return func(b *Batcher) {
+		b.block = block
+	}
+}
+
+// Batcher collects calls loaded from any goroutine and sends them as one
+// aggregate call once the batch is full or its window has passed. Call IDs
+// only have to be meaningful to the caller; the Batcher assigns its own IDs
+// internally.
+type Batcher struct {
+	mc      Multicall
+	maxSize int
+	window  time.Duration
+	block   ethrpc.BlockRef
+
+	mu      sync.Mutex
+	pending []batchEntry
+	timer   *time.Timer
+	nextID  uint64
+	closed  bool
+	flushes sync.WaitGroup
+}
+
+func NewBatcher(mc Multicall, opts ...BatcherOption) *Batcher {
+	b := &Batcher{
+		mc:      mc,
+		maxSize: DefaultChunkSize,
+		window:  DefaultBatchWindow,
+		block:   ethrpc.LatestBlock,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.maxSize <= 0 {
+		b.maxSize = DefaultChunkSize
+	}
+	return b
+}
+
+// Load adds call to the current batch and returns its pending result
+func (b *Batcher) Load(call ViewCall) *Future {
+	future := &Future{id: call.id, done: make(chan struct{})}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		future.resolve(CallResult{}, ErrBatcherClosed)
+		return future
+	}
+	call.id = strconv.FormatUint(b.nextID, 10)
+	b.nextID++
+	b.pending = append(b.pending, batchEntry{call: call, future: future})
+	if len(b.pending) >= b.maxSize {
+		b.flushLocked()
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.window, b.Flush)
+	}
+	return future
+}
+
+// Flush sends the current batch without waiting for its window to pass
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+// Close flushes the current batch and waits for every batch in flight.
+// Calls loaded afterwards fail with ErrBatcherClosed.
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.mu.Unlock()
+	b.flushes.Wait()
+}
+
+func (b *Batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	b.flushes.Add(1)
+	go func() {
+		defer b.flushes.Done()
+		b.send(batch)
+	}()
+}
+
+func (b *Batcher) send(batch []batchEntry) {
+	calls := make(ViewCalls, len(batch))
+	for i, entry := range batch {
+		calls[i] = entry.call
+	}
+	// decode errors are reported per call in CallResult.DecodeError
+	res, err := b.mc.Call(calls, b.block)
+	for _, entry := range batch {
+		if res == nil {
+			entry.future.resolve(CallResult{}, err)
+			continue
+		}
+		entry.future.resolve(res.Calls[entry.call.id], nil)
+	}
+}
@@ -0,0 +1,60 @@
+package multicall
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatcher(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+	batcher := NewBatcher(mc, MaxBatchSize(20), BatchWindow(time.Hour))
+
+	calls := numberedCalls(50)
+	futures := make([]*Future, len(calls))
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			futures[i] = batcher.Load(calls[i])
+		}(i)
+	}
+	wg.Wait()
+	batcher.Close()
+
+	assert.Equal(t, 3, eth.requests)
+	for i, future := range futures {
+		values, err := future.Values()
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), values[0].(*BigIntJSONString).ToBigInt().Int64())
+	}
+
+	_, err = batcher.Load(calls[0]).Wait()
+	assert.ErrorIs(t, err, ErrBatcherClosed)
+}
+
+func TestBatcherWindow(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+	batcher := NewBatcher(mc, BatchWindow(5*time.Millisecond))
+	defer batcher.Close()
+
+	calls := numberedCalls(3)
+	futures := make([]*Future, len(calls))
+	for i, call := range calls {
+		futures[i] = batcher.Load(call)
+	}
+	for _, future := range futures {
+		result, err := future.Wait()
+		require.NoError(t, err)
+		assert.True(t, result.Success)
+	}
+	assert.Equal(t, 1, eth.requests)
+}
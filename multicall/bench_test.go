@@ -0,0 +1,96 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// benchmarkBatch returns a batch of size calls spread over a few methods,
+// like a portfolio read, together with the aggregate result answering it.
+// With unique set every call spells its method differently, so that no
+// call finds the method compiled by another one.
+func benchmarkBatch(size int, unique bool) (ViewCalls, *AggregateResult) {
+	methods := []string{
+		"balanceOf(address)(uint256)",
+		"getReserves()(uint112 reserve0, uint112 reserve1, uint32 ts)",
+		"function allowance(address owner, address spender) view returns (uint256)",
+	}
+	word := common.LeftPadBytes(big.NewInt(1e18).Bytes(), 32)
+	returns := [][]byte{word, append(append(append([]byte{}, word...), word...), word...), word}
+	args := [][]interface{}{
+		{"0x0000000000000000000000000000000000001234"},
+		nil,
+		{"0x0000000000000000000000000000000000001234", "0x0000000000000000000000000000000000005678"},
+	}
+
+	calls := make(ViewCalls, size)
+	result := &AggregateResult{BlockNumber: big.NewInt(1), Returns: make([]AggregateReturn, size)}
+	for i := range calls {
+		method := methods[i%3]
+		if unique {
+			// surrounding spaces do not change the method
+			method = strings.Repeat(" ", i) + method
+		}
+		calls[i] = NewViewCall(fmt.Sprint(i), "0x0000000000000000000000000000000000000001", method, args[i%3])
+		result.Returns[i] = AggregateReturn{Success: true, Data: returns[i%3]}
+	}
+	return calls, result
+}
+
+// benchmarkModes runs bench with every call compiling its method, as
+// before methods were cached, and with the cached methods
+func benchmarkModes(b *testing.B, bench func(b *testing.B, calls ViewCalls, result *AggregateResult, reset func())) {
+	for _, mode := range []struct {
+		name   string
+		unique bool
+	}{{"uncached", true}, {"cached", false}} {
+		calls, result := benchmarkBatch(5000, mode.unique)
+		reset := func() {}
+		if mode.unique {
+			reset = func() {
+				b.StopTimer()
+				compiledMethods.Range(func(method, _ interface{}) bool {
+					compiledMethods.Delete(method)
+					return true
+				})
+				b.StartTimer()
+			}
+		}
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			bench(b, calls, result, reset)
+		})
+	}
+}
+
+func BenchmarkEncodeBatch(b *testing.B) {
+	aggregator := StrictAggregator(false)
+	benchmarkModes(b, func(b *testing.B, calls ViewCalls, _ *AggregateResult, reset func()) {
+		for i := 0; i < b.N; i++ {
+			reset()
+			aggregateCalls, err := calls.aggregateCalls()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := aggregator.EncodeCalls(aggregateCalls); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeBatch(b *testing.B) {
+	benchmarkModes(b, func(b *testing.B, calls ViewCalls, result *AggregateResult, reset func()) {
+		for i := 0; i < b.N; i++ {
+			reset()
+			if _, err := calls.decode(result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
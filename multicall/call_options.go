@@ -0,0 +1,112 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+// callConfig is everything CallOptions set on the eth_call of a batch
+type callConfig struct {
+	msg            ethrpc.CallMsg
+	state          ethrpc.StateOverride
+	blockOverrides *ethrpc.BlockOverrides
+	// computed are the fields Call evaluates after decoding
+	computed []computedField
+	// value is the wei sent with every call of the batch
+	value *big.Int
+}
+
+// CallOption sets a field of the eth_call sent for a batch. Options apply to
+// the aggregate call, so every call in the batch sees the multicall contract
+// as msg.sender; From only reaches view functions that are called directly
+// or that read tx.origin.
+type CallOption func(*callConfig)
+
+// From sets the sender of the eth_call
+func From(address string) CallOption {
+	return func(c *callConfig) {
+		c.msg.From = address
+	}
+}
+
+// Value sends value wei with every call of the batch, and their sum with
+// the eth_call. Only Aggregate3ValueAggregator sends value with its calls,
+// batches of other aggregators fail with it. The calls Multicall adds
+// itself, such as getBlockNumber() and those of ChainSemantics, get none.
+// TraceFailure sends value with its single call. A nil value leaves it
+// unset.
+func Value(value *big.Int) CallOption {
+	return func(c *callConfig) {
+		c.value = value
+	}
+}
+
+// CallGas overrides Config.Gas for a single call
+func CallGas(gas uint64) CallOption {
+	return func(c *callConfig) {
+		c.msg.Gas = fmt.Sprintf("0x%x", gas)
+	}
+}
+
+// GasPrice sets the legacy gas price, a nil price leaves it unset
+func GasPrice(price *big.Int) CallOption {
+	return func(c *callConfig) {
+		c.msg.GasPrice = quantity(price)
+	}
+}
+
+// MaxFeePerGas sets the EIP-1559 fee cap, a nil fee leaves it unset
+func MaxFeePerGas(fee *big.Int) CallOption {
+	return func(c *callConfig) {
+		c.msg.MaxFeePerGas = quantity(fee)
+	}
+}
+
+// MaxPriorityFeePerGas sets the EIP-1559 tip cap, a nil fee leaves it
+// unset
+func MaxPriorityFeePerGas(fee *big.Int) CallOption {
+	return func(c *callConfig) {
+		c.msg.MaxPriorityFeePerGas = quantity(fee)
+	}
+}
+
+// quantity encodes v as a hex quantity, or as the empty string the call
+// object omits when v is nil
+func quantity(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", v)
+}
+
+// AccessList sets the EIP-2930 access list
+func AccessList(list []ethrpc.AccessTuple) CallOption {
+	return func(c *callConfig) {
+		c.msg.AccessList = list
+	}
+}
+
+// OverrideState adds state to the state override set of the eth_call, e.g.
+// to read prices with an oracle's storage replaced. Later overrides of the
+// same account replace earlier ones.
+func OverrideState(state ethrpc.StateOverride) CallOption {
+	return func(c *callConfig) {
+		if c.state == nil {
+			c.state = make(ethrpc.StateOverride, len(state))
+		}
+		for address, account := range state {
+			c.state[strings.ToLower(address)] = account
+		}
+	}
+}
+
+// OverrideBlock replaces fields of the block the eth_call runs in. Block
+// overrides are a geth extension.
+func OverrideBlock(overrides ethrpc.BlockOverrides) CallOption {
+	return func(c *callConfig) {
+		c.blockOverrides = &overrides
+	}
+}
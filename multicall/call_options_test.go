@@ -0,0 +1,110 @@
+package multicall
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallOptions(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	accessList := []ethrpc.AccessTuple{{Address: "0x0000000000000000000000000000000000000001", StorageKeys: []string{}}}
+	_, err = mc.Call(numberedCalls(1), ethrpc.LatestBlock,
+		From("0x0000000000000000000000000000000000001234"),
+		CallGas(100000),
+		MaxFeePerGas(big.NewInt(30e9)),
+		MaxPriorityFeePerGas(big.NewInt(1e9)),
+		AccessList(accessList),
+	)
+	require.NoError(t, err)
+
+	msg := eth.params[0].(ethrpc.CallMsg)
+	assert.Equal(t, MainnetAddress, msg.To)
+	assert.Equal(t, "0x0000000000000000000000000000000000001234", msg.From)
+	assert.Empty(t, msg.Value)
+	assert.Equal(t, "0x186a0", msg.Gas)
+	assert.Equal(t, "0x6fc23ac00", msg.MaxFeePerGas)
+	assert.Equal(t, "0x3b9aca00", msg.MaxPriorityFeePerGas)
+	assert.Empty(t, msg.GasPrice)
+	assert.Equal(t, accessList, msg.AccessList)
+
+	_, err = mc.Call(numberedCalls(1), ethrpc.LatestBlock, GasPrice(big.NewInt(1)))
+	require.NoError(t, err)
+	msg = eth.params[0].(ethrpc.CallMsg)
+	assert.Equal(t, "0x1", msg.GasPrice)
+	assert.Equal(t, "0x400000000", msg.Gas)
+	assert.Empty(t, msg.From)
+
+	// a nil amount leaves the field unset
+	_, err = mc.Call(numberedCalls(1), ethrpc.LatestBlock, GasPrice(nil), MaxFeePerGas(nil), MaxPriorityFeePerGas(nil))
+	require.NoError(t, err)
+	msg = eth.params[0].(ethrpc.CallMsg)
+	assert.Empty(t, msg.GasPrice)
+	assert.Empty(t, msg.MaxFeePerGas)
+	assert.Empty(t, msg.MaxPriorityFeePerGas)
+}
+
+func TestCallOverrides(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth, CheckCode())
+	require.NoError(t, err)
+
+	oracle := ethrpc.StateOverride{
+		"0x00000000000000000000000000000000000000AA": {StateDiff: map[string]string{
+			"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000064",
+		}},
+	}
+	explanation, err := mc.Explain(numberedCalls(1), ethrpc.LatestBlock, OverrideState(oracle), OverrideBlock(ethrpc.BlockOverrides{Time: "0x64"}))
+	require.NoError(t, err)
+	params := explanation.Request.Params.([]interface{})
+	require.Len(t, params, 4)
+	state := params[2].(ethrpc.StateOverride)
+	assert.Len(t, state, 2)
+	assert.Equal(t, oracle["0x00000000000000000000000000000000000000AA"], state["0x00000000000000000000000000000000000000aa"])
+	assert.Equal(t, codeSizeHelperCode, state[codeSizeHelperAddress].Code)
+	assert.Equal(t, &ethrpc.BlockOverrides{Time: "0x64"}, params[3])
+
+	_, err = mc.Explain(numberedCalls(1), ethrpc.LatestBlock, OverrideState(ethrpc.StateOverride{codeSizeHelperAddress: {Balance: "0x1"}}))
+	assert.Error(t, err)
+
+	plain, err := New(eth)
+	require.NoError(t, err)
+	explanation, err = plain.Explain(numberedCalls(1), ethrpc.LatestBlock, OverrideBlock(ethrpc.BlockOverrides{BaseFee: "0x0"}))
+	require.NoError(t, err)
+	params = explanation.Request.Params.([]interface{})
+	require.Len(t, params, 4)
+	assert.Equal(t, ethrpc.StateOverride{}, params[2])
+}
+
+func TestCallValue(t *testing.T) {
+	plain, err := New(nil)
+	require.NoError(t, err)
+	_, err = plain.Explain(numberedCalls(2), ethrpc.LatestBlock, Value(big.NewInt(5)))
+	assert.Error(t, err)
+
+	mc, err := New(nil, ContractAddress(Multicall3Address), WithAggregator(Aggregate3ValueAggregator(true)))
+	require.NoError(t, err)
+	explanation, err := mc.Explain(numberedCalls(2), ethrpc.LatestBlock, Value(big.NewInt(5)))
+	require.NoError(t, err)
+	msg := explanation.Request.Params.([]interface{})[0].(ethrpc.CallMsg)
+	assert.Equal(t, "0xa", msg.Value)
+
+	// the getBlockNumber() call added last is sent no value
+	decoded, err := DecodeAggregateCallData(common.FromHex(explanation.CallData), nil)
+	require.NoError(t, err)
+	require.Len(t, decoded.Calls, 3)
+	assert.Equal(t, big.NewInt(5), decoded.Calls[0].Value)
+	assert.Equal(t, big.NewInt(5), decoded.Calls[1].Value)
+	assert.Equal(t, 0, decoded.Calls[2].Value.Sign())
+
+	explanation, err = mc.Explain(numberedCalls(2), ethrpc.LatestBlock, Value(nil))
+	require.NoError(t, err)
+	assert.Empty(t, explanation.Request.Params.([]interface{})[0].(ethrpc.CallMsg).Value)
+}
@@ -0,0 +1,209 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	// ArbSysAddress is the ArbSys precompile of Arbitrum chains
+	ArbSysAddress = "0x0000000000000000000000000000000000000064"
+	// GasPriceOracleAddress is the GasPriceOracle predeploy of OP-stack chains
+	GasPriceOracleAddress = "0x420000000000000000000000000000000000000F"
+	// L1BlockAddress is the L1Block predeploy of OP-stack chains
+	L1BlockAddress = "0x4200000000000000000000000000000000000015"
+
+	// chainCallPrefix starts the IDs of the calls of ChainSemantics, which
+	// are not reported in Result.Calls
+	chainCallPrefix = "chain."
+)
+
+// L1Info is the L1 state an L2 block was built on
+type L1Info struct {
+	BlockNumber uint64
+	// Timestamp is the timestamp of the L1 block, when the chain exposes it
+	Timestamp uint64 `json:",omitempty"`
+	// BaseFee is the L1 base fee the L2 charges L1 data with
+	BaseFee *big.Int `json:",omitempty"`
+	// Fee is the L1 data fee of the calldata given to OPStackL1Fee
+	Fee *big.Int `json:",omitempty"`
+}
+
+// ChainSemantics describes how the calls of a batch see a chain that
+// differs from Ethereum, e.g. an L2 whose block.number is the L1 block
+// number. Its Calls are sent in front of every batch, and Apply sets the
+// fields of the Result that depend on the chain from their results in
+// chain.
+type ChainSemantics interface {
+	Calls() ViewCalls
+	Apply(result *Result, chain *Result) error
+}
+
+// WithChainSemantics adds the calls of semantics to every batch and
+// applies it to every Result
+func WithChainSemantics(semantics ChainSemantics) Option {
+	return func(c *Config) {
+		c.Chain = semantics
+	}
+}
+
+type arbitrum struct{}
+
+// Arbitrum reads the L2 block number from ArbSys. Within the EVM of an
+// Arbitrum chain block.number is the number of a recent L1 block, so
+// Result.BlockNumber is set to the L2 block, EVMBlockNumber and
+// L1.BlockNumber to the block number the aggregator returned.
+func Arbitrum() ChainSemantics {
+	return arbitrum{}
+}
+
+func (arbitrum) Calls() ViewCalls {
+	return ViewCalls{
+		NewViewCall(chainCallPrefix+"arbBlockNumber", ArbSysAddress, "arbBlockNumber()(uint256)", nil),
+	}
+}
+
+func (arbitrum) Apply(result *Result, chain *Result) error {
+	number, err := chainUint(chain, "arbBlockNumber")
+	if err != nil {
+		return err
+	}
+	result.EVMBlockNumber = result.BlockNumber
+	result.L1 = &L1Info{BlockNumber: result.BlockNumber}
+	result.BlockNumber = number.Uint64()
+	return nil
+}
+
+type opStack struct {
+	l1FeeData []byte
+}
+
+type OPStackOption func(*opStack)
+
+// OPStackL1Fee adds a read of the L1 data fee of a transaction with data
+// from the GasPriceOracle, reported in L1Info.Fee
+func OPStackL1Fee(data []byte) OPStackOption {
+	return func(o *opStack) {
+		o.l1FeeData = data
+	}
+}
+
+// OPStack reads the L1 block an OP-stack block was built on from the
+// L1Block predeploy and the L1 base fee from the GasPriceOracle, reported
+// in Result.L1. block.number is the L2 block number on OP-stack chains.
+func OPStack(opts ...OPStackOption) ChainSemantics {
+	o := opStack{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o opStack) Calls() ViewCalls {
+	calls := ViewCalls{
+		NewViewCall(chainCallPrefix+"l1BlockNumber", L1BlockAddress, "number()(uint256)", nil),
+		NewViewCall(chainCallPrefix+"l1Timestamp", L1BlockAddress, "timestamp()(uint256)", nil),
+		NewViewCall(chainCallPrefix+"l1BaseFee", GasPriceOracleAddress, "l1BaseFee()(uint256)", nil),
+	}
+	if o.l1FeeData != nil {
+		calls = append(calls, NewViewCall(chainCallPrefix+"l1Fee", GasPriceOracleAddress, "getL1Fee(bytes)(uint256)", []interface{}{o.l1FeeData}))
+	}
+	return calls
+}
+
+func (o opStack) Apply(result *Result, chain *Result) error {
+	number, err := chainUint(chain, "l1BlockNumber")
+	if err != nil {
+		return err
+	}
+	timestamp, err := chainUint(chain, "l1Timestamp")
+	if err != nil {
+		return err
+	}
+	l1 := &L1Info{BlockNumber: number.Uint64(), Timestamp: timestamp.Uint64()}
+	if l1.BaseFee, err = chainUint(chain, "l1BaseFee"); err != nil {
+		return err
+	}
+	if o.l1FeeData != nil {
+		if l1.Fee, err = chainUint(chain, "l1Fee"); err != nil {
+			return err
+		}
+	}
+	result.EVMBlockNumber = result.BlockNumber
+	result.L1 = l1
+	return nil
+}
+
+// chainUint returns the single uint256 returned by the chain call name
+func chainUint(chain *Result, name string) (*big.Int, error) {
+	values, err := chain.Values(chainCallPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("call %s%s returned %d values", chainCallPrefix, name, len(values))
+	}
+	value, ok := values[0].(*BigIntJSONString)
+	if !ok {
+		return nil, fmt.Errorf("call %s%s returned %T", chainCallPrefix, name, values[0])
+	}
+	return value.ToBigInt(), nil
+}
+
+// withChainCalls puts the calls of the chain semantics in front of calls
+func (mc multicall) withChainCalls(calls ViewCalls) ViewCalls {
+	if mc.config.Chain == nil {
+		return calls
+	}
+	return append(mc.config.Chain.Calls(), calls...)
+}
+
+// splitChainResult splits the results of the chain calls in front of
+// decoded off and decodes them
+func (mc multicall) splitChainResult(decoded *AggregateResult) (*AggregateResult, *Result, error) {
+	if mc.config.Chain == nil {
+		return decoded, nil, nil
+	}
+	calls := mc.config.Chain.Calls()
+	if len(decoded.Returns) < len(calls) {
+		return nil, nil, fmt.Errorf("aggregator returned %d results for %d chain calls", len(decoded.Returns), len(calls))
+	}
+	chainDecoded, rest := *decoded, *decoded
+	chainDecoded.Returns, rest.Returns = decoded.Returns[:len(calls)], decoded.Returns[len(calls):]
+	chain, err := calls.decode(&chainDecoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rest, chain, nil
+}
+
+// applyChain applies the chain semantics to result
+func (mc multicall) applyChain(result *Result, chain *Result) error {
+	if mc.config.Chain == nil {
+		return nil
+	}
+	return mc.config.Chain.Apply(result, chain)
+}
+
+// blockNumber returns the number of the block decoded ran at, false until
+// the chain calls in front of it were answered
+func (mc multicall) blockNumber(decoded *AggregateResult) (uint64, bool, error) {
+	if mc.config.Chain == nil {
+		if decoded.BlockNumber == nil {
+			return 0, false, nil
+		}
+		return decoded.BlockNumber.Uint64(), true, nil
+	}
+	if len(decoded.Returns) < len(mc.config.Chain.Calls()) {
+		return 0, false, nil
+	}
+	_, chain, err := mc.splitChainResult(decoded)
+	if err != nil {
+		return 0, false, err
+	}
+	result := newResult(decoded)
+	if err := mc.applyChain(result, chain); err != nil {
+		return 0, false, err
+	}
+	return result.BlockNumber, true, nil
+}
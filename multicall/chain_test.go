@@ -0,0 +1,93 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respondChain answers the calls of ChainSemantics and echoes every other
+// call
+func respondChain(t *testing.T) func(target [20]byte, callData []byte) AggregateReturn {
+	uint256 := func(n int64) AggregateReturn {
+		return AggregateReturn{Success: true, Data: common.LeftPadBytes(big.NewInt(n).Bytes(), 32)}
+	}
+	return func(target [20]byte, callData []byte) AggregateReturn {
+		address := common.BytesToAddress(target[:])
+		selector := hex.EncodeToString(callData[:4])
+		switch {
+		case address == common.HexToAddress(ArbSysAddress) && selector == "a3b1b31d":
+			return uint256(123456)
+		case address == common.HexToAddress(L1BlockAddress) && selector == "8381f58a":
+			return uint256(19000000)
+		case address == common.HexToAddress(L1BlockAddress) && selector == "b80777ea":
+			return uint256(1700000000)
+		case address == common.HexToAddress(GasPriceOracleAddress) && selector == "519b4bd3":
+			return uint256(7e9)
+		case address == common.HexToAddress(GasPriceOracleAddress) && selector == "49948e0e":
+			bytesType, err := abi.NewType("bytes", "", nil)
+			require.NoError(t, err)
+			values, err := abi.Arguments{{Type: bytesType}}.Unpack(callData[4:])
+			require.NoError(t, err)
+			return uint256(int64(len(values[0].([]byte))) * 1000)
+		}
+		return echoArgument(target, callData)
+	}
+}
+
+func TestArbitrum(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: respondChain(t)}
+	mc, err := New(eth, WithChainSemantics(Arbitrum()))
+	require.NoError(t, err)
+
+	res, err := mc.Call(numberedCalls(3), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123456), res.BlockNumber)
+	assert.Equal(t, uint64(42), res.EVMBlockNumber)
+	assert.Equal(t, &L1Info{BlockNumber: 42}, res.L1)
+	assert.Len(t, res.Calls, 3)
+	assert.Equal(t, int64(2), res.Calls["2"].Decoded[0].(*BigIntJSONString).ToBigInt().Int64())
+
+	raw, err := mc.CallRaw(numberedCalls(3), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123456), raw.BlockNumber)
+	assert.Len(t, raw.Calls, 3)
+
+	// chunks after the first are pinned to the L2 block
+	mc, err = New(eth, WithChainSemantics(Arbitrum()), ChunkSize(2), Adaptive(NewLimitStore(), "arbitrum"))
+	require.NoError(t, err)
+	res, err = mc.Call(numberedCalls(5), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123456), res.BlockNumber)
+	assert.Len(t, res.Calls, 5)
+	assert.Equal(t, ethrpc.BlockNumberRef(123456), eth.params[1])
+}
+
+func TestOPStack(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: respondChain(t)}
+	mc, err := New(eth, WithChainSemantics(OPStack(OPStackL1Fee([]byte{1, 2, 3}))))
+	require.NoError(t, err)
+
+	res, err := mc.Call(numberedCalls(2), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), res.BlockNumber)
+	assert.Equal(t, uint64(42), res.EVMBlockNumber)
+	assert.Equal(t, &L1Info{
+		BlockNumber: 19000000,
+		Timestamp:   1700000000,
+		BaseFee:     big.NewInt(7e9),
+		Fee:         big.NewInt(3000),
+	}, res.L1)
+	assert.Len(t, res.Calls, 2)
+
+	explanation, err := mc.Explain(numberedCalls(2), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Len(t, explanation.Calls, 6)
+	assert.Equal(t, "chain.l1BlockNumber", explanation.Calls[0].ID)
+}
@@ -0,0 +1,103 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+// CallStatus is the outcome of a single call
+type CallStatus int
+
+const (
+	// StatusSuccess is a call that returned successfully
+	StatusSuccess CallStatus = iota
+	// StatusFailed is a call that reverted
+	StatusFailed
+	// StatusNoCode is a call to an address without code, which would
+	// otherwise succeed with empty return data
+	StatusNoCode
+)
+
+func (s CallStatus) String() string {
+	switch s {
+	case StatusSuccess:
+		return "success"
+	case StatusFailed:
+		return "failed"
+	case StatusNoCode:
+		return "no code"
+	}
+	return fmt.Sprintf("CallStatus(%d)", int(s))
+}
+
+func (s CallStatus) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", s.String())), nil
+}
+
+const (
+	// codeSizeHelperAddress is where the code size helper is placed by the
+	// state override, an address no contract is deployed at
+	codeSizeHelperAddress = "0x00000000000000000000000000000000c0de5123"
+	// codeSizeHelperCode returns extcodesize of the address argument:
+	// PUSH1 4 CALLDATALOAD EXTCODESIZE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	codeSizeHelperCode = "0x6004353b60005260206000f3"
+	// codeSizeSelector is the selector of extcodesize(address), the helper
+	// ignores it but keeps the calldata ABI shaped
+	codeSizeSelector = "\xab\x11\x22\xd9"
+)
+
+func uniqueTargets(calls []AggregateCall) [][20]byte {
+	seen := make(map[[20]byte]bool, len(calls))
+	targets := make([][20]byte, 0, len(calls))
+	for _, call := range calls {
+		if seen[call.Target] {
+			continue
+		}
+		seen[call.Target] = true
+		targets = append(targets, call.Target)
+	}
+	return targets
+}
+
+func codeSizeCalls(targets [][20]byte) []AggregateCall {
+	helper := common.HexToAddress(codeSizeHelperAddress)
+	calls := make([]AggregateCall, len(targets))
+	for i, target := range targets {
+		callData := append([]byte(codeSizeSelector), common.LeftPadBytes(target[:], 32)...)
+		calls[i] = AggregateCall{Target: helper, CallData: callData}
+	}
+	return calls
+}
+
+func codeSizeOverride() ethrpc.StateOverride {
+	return ethrpc.StateOverride{
+		codeSizeHelperAddress: {Code: codeSizeHelperCode},
+	}
+}
+
+// markNoCode strips the code size results appended for targets from
+// decoded and marks the calls whose target has no code
+func markNoCode(decoded *AggregateResult, calls []AggregateCall, targets [][20]byte) error {
+	sizes := decoded.Returns[len(calls):]
+	noCode := make(map[[20]byte]bool, len(targets))
+	for i, target := range targets {
+		if !sizes[i].Success || len(sizes[i].Data) != 32 {
+			return fmt.Errorf("code size check failed for %x, the node may not support state overrides", target)
+		}
+		if new(big.Int).SetBytes(sizes[i].Data).Sign() == 0 {
+			noCode[target] = true
+		}
+	}
+
+	decoded.Returns = decoded.Returns[:len(calls)]
+	for i, call := range calls {
+		if noCode[call.Target] {
+			decoded.Returns[i].Success = false
+			decoded.Returns[i].NoCode = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package multicall
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCode(t *testing.T) {
+	contract := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	eoa := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	eth := &fakeETH{blockNumber: 42, respond: func(target [20]byte, callData []byte) AggregateReturn {
+		if common.Address(target) == common.HexToAddress(codeSizeHelperAddress) {
+			size := int64(0)
+			if common.BytesToAddress(callData[4:]) == contract {
+				size = 100
+			}
+			return AggregateReturn{Success: true, Data: common.LeftPadBytes(big.NewInt(size).Bytes(), 32)}
+		}
+		if common.Address(target) == eoa {
+			return AggregateReturn{Success: true}
+		}
+		return echoArgument(target, callData)
+	}}
+	mc, err := New(eth, CheckCode())
+	require.NoError(t, err)
+
+	calls := ViewCalls{
+		NewViewCall("contract", contract.Hex(), "get(uint256)(uint256)", []interface{}{1}),
+		NewViewCall("eoa", eoa.Hex(), "get(uint256)(uint256)", []interface{}{2}),
+		NewViewCall("contract-again", contract.Hex(), "get(uint256)(uint256)", []interface{}{3}),
+	}
+	res, err := mc.Call(calls, ethrpc.LatestBlock)
+	require.NoError(t, err)
+	require.Len(t, res.Calls, 3)
+	assert.Equal(t, StatusSuccess, res.Calls["contract"].Status)
+	assert.Equal(t, StatusSuccess, res.Calls["contract-again"].Status)
+	assert.Equal(t, StatusNoCode, res.Calls["eoa"].Status)
+	assert.False(t, res.Calls["eoa"].Success)
+	assert.Len(t, eth.params, 3)
+}
@@ -0,0 +1,625 @@
+package multicall
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxExponent bounds the exponent of ^ and pow
+	maxExponent = 1024
+	// maxPowBits bounds the size of a power, as the bits of its base times
+	// its exponent, so that chained powers cannot exhaust memory
+	maxPowBits = 1 << 16
+	// computedPrecision is the number of decimals computed values are
+	// marshalled to JSON with
+	computedPrecision = 18
+)
+
+// ComputedResult is the value of a computed field, or the error that kept
+// it from being evaluated
+type ComputedResult struct {
+	Value *big.Rat
+	Error error
+}
+
+// Float64 returns the value as the nearest float64
+func (c ComputedResult) Float64() float64 {
+	if c.Value == nil {
+		return 0
+	}
+	f, _ := c.Value.Float64()
+	return f
+}
+
+// MarshalJSON writes the value as a decimal string with up to 18 decimals
+func (c ComputedResult) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Value string `json:",omitempty"`
+		Error string `json:",omitempty"`
+	}{}
+	if c.Value != nil {
+		out.Value = strings.TrimRight(strings.TrimRight(c.Value.FloatString(computedPrecision), "0"), ".")
+	}
+	if c.Error != nil {
+		out.Error = c.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// Compute adds a field evaluated from expression once the calls are
+// decoded, reported in Result.Computed under id. See ParseExpression for
+// the syntax. Computed fields are evaluated by Call and Pipeline.Run, and
+// CallRaw and CallStream reject them.
+func Compute(id, expression string) CallOption {
+	return func(c *callConfig) {
+		c.computed = append(c.computed, computedField{id: id, source: expression})
+	}
+}
+
+type computedField struct {
+	id         string
+	source     string
+	expression *Expression
+}
+
+// computedFields parses the computed fields set by opts, failing when one
+// does not parse or reuses the ID of a call
+func computedFields(opts []CallOption, calls ViewCalls) ([]computedField, error) {
+	config := &callConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	ids := make(map[string]bool, len(calls)+len(config.computed))
+	for _, call := range calls {
+		ids[call.id] = true
+	}
+	for i, field := range config.computed {
+		if ids[field.id] {
+			return nil, fmt.Errorf("computed field %s: duplicate id", field.id)
+		}
+		ids[field.id] = true
+		expression, err := ParseExpression(field.source)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %s: %w", field.id, err)
+		}
+		config.computed[i].expression = expression
+	}
+	return config.computed, nil
+}
+
+// rejectComputed fails when opts add computed fields, which method does
+// not evaluate
+func rejectComputed(opts []CallOption, method string) error {
+	config := &callConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if len(config.computed) > 0 {
+		return fmt.Errorf("%s does not evaluate computed fields", method)
+	}
+	return nil
+}
+
+// withoutComputed wraps opts so that they leave out the computed fields,
+// for the inner calls of methods that evaluate them over the calls of
+// several batches
+func withoutComputed(opts []CallOption) []CallOption {
+	stripped := make([]CallOption, len(opts))
+	for i, opt := range opts {
+		opt := opt
+		stripped[i] = func(c *callConfig) {
+			computed := c.computed
+			opt(c)
+			c.computed = computed
+		}
+	}
+	return stripped
+}
+
+// compute evaluates fields over result into result.Computed
+func (result *Result) compute(fields []computedField) {
+	if len(fields) == 0 {
+		return
+	}
+	env := &computeEnv{
+		result:   result,
+		fields:   make(map[string]*Expression, len(fields)),
+		visiting: make(map[string]bool),
+	}
+	result.Computed = make(map[string]ComputedResult, len(fields))
+	for _, field := range fields {
+		env.fields[field.id] = field.expression
+	}
+	for _, field := range fields {
+		if _, ok := result.Computed[field.id]; !ok {
+			env.field(field.id)
+		}
+	}
+}
+
+// Expression is a parsed computed field expression
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ParseExpression parses an arithmetic expression over the results of a
+// batch:
+//   - {id} is the first return value of the call id, or the computed field
+//     id; {id}[1] is its second and {id}.name its named return value.
+//     Further [i] and .name select list elements and tuple fields.
+//   - numbers are decimal, e.g. 1.5
+//   - + - * / and ^ (integer exponents), with the usual precedence and ^
+//     binding tightest
+//   - pow(x, n), min(x, ...), max(x, ...) and abs(x)
+//
+// For example "{balance} / 10^{decimals} * {price}[1]".
+func ParseExpression(source string) (*Expression, error) {
+	p := &exprParser{source: source}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.source) {
+		return nil, p.errorf("unexpected %q", p.source[p.pos])
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression over result. References to computed
+// fields read result.Computed.
+func (e *Expression) Evaluate(result *Result) (*big.Rat, error) {
+	return e.root.eval(&computeEnv{result: result, visiting: make(map[string]bool)})
+}
+
+type computeEnv struct {
+	result *Result
+	// fields are the computed fields still to be evaluated
+	fields   map[string]*Expression
+	visiting map[string]bool
+}
+
+// field evaluates the computed field id, once
+func (env *computeEnv) field(id string) (*big.Rat, error) {
+	if computed, ok := env.result.Computed[id]; ok {
+		if computed.Error != nil {
+			return nil, fmt.Errorf("computed field %s: %w", id, computed.Error)
+		}
+		return computed.Value, nil
+	}
+	expression, ok := env.fields[id]
+	if !ok {
+		return nil, fmt.Errorf("no result for call %s", id)
+	}
+	if env.visiting[id] {
+		return nil, fmt.Errorf("computed field %s depends on itself", id)
+	}
+	env.visiting[id] = true
+	value, err := expression.root.eval(env)
+	delete(env.visiting, id)
+	if env.result.Computed != nil {
+		env.result.Computed[id] = ComputedResult{Value: value, Error: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("computed field %s: %w", id, err)
+	}
+	return value, nil
+}
+
+type exprNode interface {
+	eval(env *computeEnv) (*big.Rat, error)
+}
+
+type numberNode struct {
+	value *big.Rat
+}
+
+func (n numberNode) eval(*computeEnv) (*big.Rat, error) {
+	return n.value, nil
+}
+
+// selector is a [index] or .name accessor of a reference
+type selector struct {
+	index int
+	name  string
+}
+
+type refNode struct {
+	id        string
+	selectors []selector
+}
+
+func (n refNode) eval(env *computeEnv) (*big.Rat, error) {
+	callResult, ok := env.result.Calls[n.id]
+	if !ok {
+		if len(n.selectors) > 0 {
+			if _, ok := env.fields[n.id]; ok {
+				return nil, fmt.Errorf("computed field %s has no return values to select", n.id)
+			}
+		}
+		return env.field(n.id)
+	}
+	values, err := callResult.values(n.id)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := n.selectors
+	var value interface{}
+	switch {
+	case len(selectors) == 0:
+		if len(values) == 0 {
+			return nil, fmt.Errorf("call %s returned no values", n.id)
+		}
+		value = values[0]
+	case selectors[0].name != "":
+		if value, ok = callResult.Named[selectors[0].name]; !ok {
+			return nil, fmt.Errorf("call %s has no return value %s", n.id, selectors[0].name)
+		}
+		selectors = selectors[1:]
+	default:
+		value = values
+	}
+
+	for _, sel := range selectors {
+		switch v := value.(type) {
+		case []interface{}:
+			if sel.name != "" || sel.index >= len(v) {
+				return nil, fmt.Errorf("call %s: cannot select %s from a list of %d values", n.id, sel, len(v))
+			}
+			value = v[sel.index]
+		case map[string]interface{}:
+			field, ok := v[sel.name]
+			if sel.name == "" || !ok {
+				return nil, fmt.Errorf("call %s: cannot select %s from a tuple", n.id, sel)
+			}
+			value = field
+		default:
+			return nil, fmt.Errorf("call %s: cannot select %s from %T", n.id, sel, value)
+		}
+	}
+
+	integer, ok := asInteger(value)
+	if !ok {
+		return nil, fmt.Errorf("call %s: %T is not a number", n.id, value)
+	}
+	return new(big.Rat).SetInt(integer), nil
+}
+
+func (s selector) String() string {
+	if s.name != "" {
+		return "." + s.name
+	}
+	return fmt.Sprintf("[%d]", s.index)
+}
+
+type unaryNode struct {
+	x exprNode
+}
+
+func (n unaryNode) eval(env *computeEnv) (*big.Rat, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Neg(x), nil
+}
+
+type binaryNode struct {
+	op   byte
+	x, y exprNode
+}
+
+func (n binaryNode) eval(env *computeEnv) (*big.Rat, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case '+':
+		return new(big.Rat).Add(x, y), nil
+	case '-':
+		return new(big.Rat).Sub(x, y), nil
+	case '*':
+		return new(big.Rat).Mul(x, y), nil
+	case '/':
+		if y.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return new(big.Rat).Quo(x, y), nil
+	}
+	return pow(x, y)
+}
+
+type funcNode struct {
+	name string
+	args []exprNode
+}
+
+func (n funcNode) eval(env *computeEnv) (*big.Rat, error) {
+	args := make([]*big.Rat, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	switch n.name {
+	case "pow":
+		return pow(args[0], args[1])
+	case "abs":
+		return new(big.Rat).Abs(args[0]), nil
+	}
+	value := args[0]
+	for _, arg := range args[1:] {
+		if cmp := arg.Cmp(value); (n.name == "min" && cmp < 0) || (n.name == "max" && cmp > 0) {
+			value = arg
+		}
+	}
+	return value, nil
+}
+
+// pow raises x to the integer exponent y
+func pow(x, y *big.Rat) (*big.Rat, error) {
+	if !y.IsInt() {
+		return nil, fmt.Errorf("exponent %s is not an integer", y.RatString())
+	}
+	exponent := y.Num()
+	if exponent.CmpAbs(big.NewInt(maxExponent)) > 0 {
+		return nil, fmt.Errorf("exponent %s exceeds %d", exponent, maxExponent)
+	}
+	n := new(big.Int).Abs(exponent)
+	bits := x.Num().BitLen()
+	if denomBits := x.Denom().BitLen(); denomBits > bits {
+		bits = denomBits
+	}
+	if uint64(bits)*n.Uint64() > maxPowBits {
+		return nil, fmt.Errorf("power of a %d bit base to %s exceeds %d bits", bits, exponent, maxPowBits)
+	}
+	result := new(big.Rat).SetFrac(new(big.Int).Exp(x.Num(), n, nil), new(big.Int).Exp(x.Denom(), n, nil))
+	if exponent.Sign() < 0 {
+		if result.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		result.Inv(result)
+	}
+	return result, nil
+}
+
+// functionArity is the number of arguments of every function, -1 for at
+// least one
+var functionArity = map[string]int{
+	"pow": 2,
+	"abs": 1,
+	"min": -1,
+	"max": -1,
+}
+
+type exprParser struct {
+	source string
+	pos    int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expression %q at %d: %s", p.source, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.source) && unicode.IsSpace(rune(p.source[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes c if it comes next
+func (p *exprParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.source) && p.source[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	x, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept('+'):
+			op = '+'
+		case p.accept('-'):
+			op = '-'
+		default:
+			return x, nil
+		}
+		y, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		default:
+			return x, nil
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept('-') {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{x: x}, nil
+	}
+	return p.parsePower()
+}
+
+// parsePower parses x^y, which is right associative and binds tighter
+// than a leading minus, so -2^2 is -4
+func (p *exprParser) parsePower() (exprNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept('^') {
+		return x, nil
+	}
+	y, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: '^', x: x, y: y}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.source) {
+		return nil, p.errorf("unexpected end")
+	}
+	c := p.source[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	case c == '{':
+		return p.parseRef()
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.source) && (p.source[p.pos] >= '0' && p.source[p.pos] <= '9' || p.source[p.pos] == '.') {
+			p.pos++
+		}
+		value, ok := new(big.Rat).SetString(p.source[start:p.pos])
+		if !ok {
+			return nil, p.errorf("invalid number %s", p.source[start:p.pos])
+		}
+		return numberNode{value: value}, nil
+	case unicode.IsLetter(rune(c)):
+		return p.parseFunc()
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+func (p *exprParser) parseRef() (exprNode, error) {
+	end := strings.IndexByte(p.source[p.pos:], '}')
+	if end < 0 {
+		return nil, p.errorf("missing }")
+	}
+	ref := refNode{id: p.source[p.pos+1 : p.pos+end]}
+	if ref.id == "" {
+		return nil, p.errorf("empty call id")
+	}
+	p.pos += end + 1
+	for p.pos < len(p.source) {
+		switch p.source[p.pos] {
+		case '[':
+			end := strings.IndexByte(p.source[p.pos:], ']')
+			if end < 0 {
+				return nil, p.errorf("missing ]")
+			}
+			index, err := strconv.Atoi(p.source[p.pos+1 : p.pos+end])
+			if err != nil || index < 0 {
+				return nil, p.errorf("invalid index %s", p.source[p.pos+1:p.pos+end])
+			}
+			ref.selectors = append(ref.selectors, selector{index: index})
+			p.pos += end + 1
+		case '.':
+			p.pos++
+			name := p.identifier()
+			if name == "" {
+				return nil, p.errorf("missing name after .")
+			}
+			ref.selectors = append(ref.selectors, selector{name: name})
+		default:
+			return ref, nil
+		}
+	}
+	return ref, nil
+}
+
+func (p *exprParser) parseFunc() (exprNode, error) {
+	start := p.pos
+	name := p.identifier()
+	arity, ok := functionArity[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %s", name)
+	}
+	if !p.accept('(') {
+		return nil, p.errorf("missing ( after %s", name)
+	}
+	node := funcNode{name: name}
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+		if p.accept(')') {
+			break
+		}
+		if !p.accept(',') {
+			return nil, p.errorf("missing , or )")
+		}
+	}
+	if arity >= 0 && len(node.args) != arity {
+		return nil, p.errorf("%s takes %d arguments, got %d", name, arity, len(node.args))
+	}
+	return node, nil
+}
+
+func (p *exprParser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.source) {
+		c := rune(p.source[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			break
+		}
+		p.pos++
+	}
+	return p.source[start:p.pos]
+}
@@ -0,0 +1,128 @@
+package multicall
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompute(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	res, err := mc.Call(numberedCalls(4), ethrpc.LatestBlock,
+		Compute("scaled", "{2} / 10^{1} * {sum}"),
+		Compute("sum", "{1} + {2} * {3}"),
+		Compute("ratio", "({3} - {1}) / {2}"),
+		Compute("functions", "pow(2, -2) + max({1}, {3}, 2) + min({0}, 1) + abs(-2^2)"),
+		Compute("zero", "{1} / {0}"),
+		Compute("missing", "{4}"),
+		Compute("dependent", "{zero} + 1"),
+		Compute("loop", "{loop} + 1"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(7, 1), res.Computed["sum"].Value)
+	assert.Equal(t, big.NewRat(7, 5), res.Computed["scaled"].Value)
+	assert.Equal(t, big.NewRat(1, 1), res.Computed["ratio"].Value)
+	assert.Equal(t, big.NewRat(29, 4), res.Computed["functions"].Value)
+	assert.Equal(t, 7.25, res.Computed["functions"].Float64())
+	for _, id := range []string{"zero", "missing", "dependent", "loop"} {
+		assert.Error(t, res.Computed[id].Error, id)
+		assert.Nil(t, res.Computed[id].Value, id)
+	}
+
+	out, err := json.Marshal(res.Computed["scaled"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Value": "1.4"}`, string(out))
+
+	// invalid expressions fail before anything is sent
+	requests := eth.requests
+	for _, expression := range []string{"{1} +", "{1", "foo(1)", "max()", "1 2", "{1}[x]"} {
+		_, err = mc.Call(numberedCalls(2), ethrpc.LatestBlock, Compute("bad", expression))
+		assert.Error(t, err, expression)
+	}
+	_, err = mc.Call(numberedCalls(2), ethrpc.LatestBlock, Compute("1", "1"))
+	assert.Error(t, err)
+	assert.Equal(t, requests, eth.requests)
+}
+
+func TestExpressionSelectors(t *testing.T) {
+	result := &Result{Calls: map[string]CallResult{
+		"reserves": {
+			Success: true,
+			Decoded: []interface{}{bigJSON(100), bigJSON(400), uint32(7)},
+			Named:   map[string]interface{}{"reserve0": bigJSON(100), "reserve1": bigJSON(400), "ts": uint32(7)},
+		},
+		"pool": {
+			Success: true,
+			Decoded: []interface{}{map[string]interface{}{"fee": uint8(3), "ticks": []interface{}{bigJSON(-5), bigJSON(10)}}},
+		},
+		"failed": {Success: false, Status: StatusFailed},
+	}}
+
+	for expression, expected := range map[string]*big.Rat{
+		"{reserves}.reserve1 / {reserves}.reserve0": big.NewRat(4, 1),
+		"{reserves}[1] - {reserves}[0]":             big.NewRat(300, 1),
+		"{reserves}.ts * 2":                         big.NewRat(14, 1),
+		"{pool}[0].fee / 1000":                      big.NewRat(3, 1000),
+		"{pool}[0].ticks[0] * {pool}[0].ticks[1]":   big.NewRat(-50, 1),
+	} {
+		parsed, err := ParseExpression(expression)
+		require.NoError(t, err, expression)
+		value, err := parsed.Evaluate(result)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, value, expression)
+	}
+
+	for _, expression := range []string{"{failed}", "{reserves}.missing", "{reserves}[3]", "{pool}", "{pool}[0].ticks[2]", "2^0.5", "2^2000", "((2^1024)^1024)^1024", "pow(3^1000, 1000)"} {
+		parsed, err := ParseExpression(expression)
+		require.NoError(t, err, expression)
+		_, err = parsed.Evaluate(result)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestPipelineCompute(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	calls := numberedCalls(4)
+	res, err := NewPipeline(mc).
+		Add("first", calls[:2]).
+		Derive("second", []string{"first"}, func(*Result) (ViewCalls, error) {
+			return calls[2:], nil
+		}).
+		Run(ethrpc.LatestBlock, Compute("total", "{1} + {3}"))
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(4, 1), res.Computed["total"].Value)
+}
+
+func TestComputeUnsupported(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	compute := Compute("total", "{0} + {1}")
+	_, err = mc.CallRaw(numberedCalls(2), ethrpc.LatestBlock, compute)
+	assert.Error(t, err)
+
+	stream := mc.CallStream(numberedCalls(2), ethrpc.LatestBlock, compute)
+	for range stream.Results {
+		t.Fatal("a stream with computed fields emitted a result")
+	}
+	assert.Error(t, <-stream.Errors)
+
+	// the inner calls of a pipeline only get the other options
+	config := &callConfig{}
+	for _, opt := range withoutComputed([]CallOption{compute, CallGas(100000)}) {
+		opt(config)
+	}
+	assert.Empty(t, config.computed)
+	assert.Equal(t, "0x186a0", config.msg.Gas)
+}
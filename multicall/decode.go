@@ -0,0 +1,237 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MethodRegistry maps function selectors such as "0x70a08231" to method
+// strings in any form NewViewCall accepts
+type MethodRegistry map[string]string
+
+// NewMethodRegistry registers methods under their selectors
+func NewMethodRegistry(methods ...string) (MethodRegistry, error) {
+	registry := make(MethodRegistry, len(methods))
+	for _, method := range methods {
+		if err := registry.Add(method); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Add registers method under its selector
+func (r MethodRegistry) Add(method string) error {
+	selector, err := NewViewCall("", "", method, nil).methodCallData()
+	if err != nil {
+		return err
+	}
+	r["0x"+hex.EncodeToString(selector)] = method
+	return nil
+}
+
+// AddABI registers every method of contract
+func (r MethodRegistry) AddABI(contract abi.ABI) error {
+	for _, method := range contract.Methods {
+		inputs := make([]string, len(method.Inputs))
+		for i, input := range method.Inputs {
+			inputs[i] = input.Type.String()
+		}
+		outputs := make([]string, len(method.Outputs))
+		for i, output := range method.Outputs {
+			outputs[i] = output.Type.String()
+		}
+		if err := r.Add(fmt.Sprintf("%s(%s)(%s)", method.RawName, strings.Join(inputs, ","), strings.Join(outputs, ","))); err != nil {
+			return fmt.Errorf("method %s: %w", method.Name, err)
+		}
+	}
+	return nil
+}
+
+// DecodedCall is a single call unpacked from aggregate calldata
+type DecodedCall struct {
+	Target   string
+	CallData []byte
+	// AllowFailure is set when the call may fail without reverting the
+	// whole aggregate call
+	AllowFailure bool
+	// Value is the ether sent along by aggregate3Value, nil otherwise
+	Value *big.Int
+	// Method is the registered method string of the selector, empty when
+	// the selector is unknown
+	Method string
+	// Arguments are the decoded arguments when Method is set
+	Arguments []interface{}
+	// Result is set by DecodeOutput
+	Result *CallResult
+
+	call ViewCall
+}
+
+// DecodedAggregate is aggregate calldata unpacked by DecodeAggregateCallData
+type DecodedAggregate struct {
+	// Function is the name of the aggregate function, e.g. "aggregate3"
+	Function string
+	Selector string
+	Calls    []DecodedCall
+
+	aggregator Aggregator
+}
+
+type aggregateFunction struct {
+	name   string
+	inputs abi.Arguments
+	// aggregator decodes what the function returns
+	aggregator Aggregator
+}
+
+var aggregateFunctions = map[string]aggregateFunction{
+	AggregateMethod:            {"aggregate", abi.Arguments{{Type: callListType}, {Type: boolType}}, StrictAggregator(false)},
+	MakerAggregateMethod:       {"aggregate", abi.Arguments{{Type: callListType}}, MakerAggregator()},
+	TryAggregateMethod:         {"tryAggregate", abi.Arguments{{Type: boolType}, {Type: callListType}}, TryAggregator(false)},
+	BlockAndAggregateMethod:    {"blockAndAggregate", abi.Arguments{{Type: callListType}}, BlockAndAggregator()},
+	TryBlockAndAggregateMethod: {"tryBlockAndAggregate", abi.Arguments{{Type: boolType}, {Type: callListType}}, TryBlockAndAggregator(false)},
+	Aggregate3Method:           {"aggregate3", abi.Arguments{{Type: call3ListType}}, Aggregate3Aggregator(false)},
+	Aggregate3ValueMethod:      {"aggregate3Value", abi.Arguments{{Type: call3ValueListType}}, Aggregate3ValueAggregator(false)},
+}
+
+// DecodeAggregateCallData unpacks the calldata of any aggregate function
+// supported by the aggregators of this package, e.g. the input of a
+// transaction or trace calling a multicall contract. Calls whose selector is
+// in registry also get their method and decoded arguments; registry may be
+// nil.
+func DecodeAggregateCallData(input []byte, registry MethodRegistry) (*DecodedAggregate, error) {
+	if len(input) < 4 {
+		return nil, fmt.Errorf("calldata too short: %d bytes", len(input))
+	}
+	selector := "0x" + hex.EncodeToString(input[:4])
+	function, ok := aggregateFunctions[selector]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate function selector %s", selector)
+	}
+	values, err := function.inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", function.name, err)
+	}
+
+	// a leading or trailing bool is strict or requireSuccess, both
+	// disallowing failures when set
+	allowFailure := false
+	var calls interface{}
+	for _, value := range values {
+		if flag, ok := value.(bool); ok {
+			allowFailure = !flag
+		} else {
+			calls = value
+		}
+	}
+	if selector == MakerAggregateMethod || selector == BlockAndAggregateMethod {
+		allowFailure = false
+	}
+
+	decoded := &DecodedAggregate{
+		Function:   function.name,
+		Selector:   selector,
+		aggregator: function.aggregator,
+	}
+	list := reflect.ValueOf(calls)
+	for i := 0; i < list.Len(); i++ {
+		call, err := decodeCall(list.Index(i), allowFailure, registry)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		decoded.Calls = append(decoded.Calls, *call)
+	}
+	return decoded, nil
+}
+
+func decodeCall(elem reflect.Value, allowFailure bool, registry MethodRegistry) (*DecodedCall, error) {
+	var target common.Address
+	reflect.Copy(reflect.ValueOf(target[:]), elem.FieldByName("Target"))
+	call := &DecodedCall{
+		Target:       target.Hex(),
+		CallData:     elem.FieldByName("CallData").Bytes(),
+		AllowFailure: allowFailure,
+	}
+	if field := elem.FieldByName("AllowFailure"); field.IsValid() {
+		call.AllowFailure = field.Bool()
+	}
+	if field := elem.FieldByName("Value"); field.IsValid() {
+		call.Value = field.Interface().(*big.Int)
+	}
+	if len(call.CallData) < 4 || registry == nil {
+		return call, nil
+	}
+	method, ok := registry["0x"+hex.EncodeToString(call.CallData[:4])]
+	if !ok {
+		return call, nil
+	}
+
+	compiled, err := compileMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	values, err := compiled.args.Unpack(call.CallData[4:])
+	if err != nil {
+		return nil, fmt.Errorf("decoding arguments of %s: %w", method, err)
+	}
+	call.Method = method
+	call.Arguments = make([]interface{}, len(values))
+	// the arguments of the ViewCall have to be accepted by getArgument
+	arguments := make([]interface{}, len(values))
+	for i, value := range values {
+		call.Arguments[i] = normalizeValue(value, compiled.args[i].Type, compiled.sig.args[i])
+		arguments[i] = value
+		if address, ok := value.(common.Address); ok {
+			arguments[i] = address.Hex()
+		}
+	}
+	call.call = NewViewCall("", call.Target, method, arguments)
+	return call, nil
+}
+
+// DecodeOutput decodes the return data of the aggregate call into the
+// Result of every call. The return values of calls with a Method are
+// decoded as well.
+func (d *DecodedAggregate) DecodeOutput(output []byte) (*AggregateResult, error) {
+	decoded, err := d.aggregator.DecodeResults(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded.Returns) != len(d.Calls) {
+		return nil, fmt.Errorf("output holds %d results for %d calls", len(decoded.Returns), len(d.Calls))
+	}
+	for i, ret := range decoded.Returns {
+		result := &CallResult{
+			Success: ret.Success,
+			Status:  ret.status(),
+			Raw:     ret.Data,
+		}
+		if ret.Success && d.Calls[i].Method != "" {
+			result.Decoded, result.Named, result.DecodeError = d.Calls[i].call.decode(ret.Data)
+		}
+		d.Calls[i].Result = result
+	}
+	return decoded, nil
+}
+
+// ViewCalls returns the calls with a known method as ViewCalls, with their
+// index in Calls as ID
+func (d *DecodedAggregate) ViewCalls() ViewCalls {
+	var calls ViewCalls
+	for i, call := range d.Calls {
+		if call.Method == "" {
+			continue
+		}
+		viewCall := call.call
+		viewCall.id = fmt.Sprint(i)
+		calls = append(calls, viewCall)
+	}
+	return calls
+}
@@ -0,0 +1,99 @@
+package multicall
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const erc20ABI = `[{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}]`
+
+func TestDecodeAggregateCallData(t *testing.T) {
+	owner := "0x0000000000000000000000000000000000001234"
+	calls := ViewCalls{
+		NewViewCall("balance", "0x0000000000000000000000000000000000000001", "balanceOf(address)(uint256)", []interface{}{owner}),
+		NewViewCall("unknown", "0x0000000000000000000000000000000000000002", "totalSupply()(uint256)", nil),
+	}
+	aggregateCalls, err := calls.aggregateCalls()
+	require.NoError(t, err)
+
+	contract, err := abi.JSON(strings.NewReader(erc20ABI))
+	require.NoError(t, err)
+	registry := MethodRegistry{}
+	require.NoError(t, registry.AddABI(contract))
+
+	var tests = map[string]struct {
+		aggregator   Aggregator
+		function     string
+		allowFailure bool
+	}{
+		"aggregate":    {StrictAggregator(true), "aggregate", false},
+		"tryAggregate": {TryAggregator(false), "tryAggregate", true},
+		"aggregate3":   {Aggregate3Aggregator(true), "aggregate3", true},
+		"maker":        {MakerAggregator(), "aggregate", false},
+	}
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			input, err := tt.aggregator.EncodeCalls(aggregateCalls)
+			require.NoError(t, err)
+
+			decoded, err := DecodeAggregateCallData(input, registry)
+			require.NoError(t, err)
+			assert.Equal(t, tt.function, decoded.Function)
+			require.Len(t, decoded.Calls, 2)
+			for i, call := range decoded.Calls {
+				assert.Equal(t, common.BytesToAddress(aggregateCalls[i].Target[:]).Hex(), call.Target)
+				assert.Equal(t, aggregateCalls[i].CallData, call.CallData)
+				assert.Equal(t, tt.allowFailure, call.AllowFailure)
+			}
+			assert.Equal(t, "balanceOf(address)(uint256)", decoded.Calls[0].Method)
+			assert.Equal(t, []interface{}{common.HexToAddress(owner)}, decoded.Calls[0].Arguments)
+			assert.Empty(t, decoded.Calls[1].Method)
+
+			viewCalls := decoded.ViewCalls()
+			require.Len(t, viewCalls, 1)
+			callData, err := viewCalls[0].callData()
+			require.NoError(t, err)
+			assert.Equal(t, aggregateCalls[0].CallData, callData)
+		})
+	}
+}
+
+func TestDecodeAggregate3Value(t *testing.T) {
+	calls := []struct {
+		Target       common.Address
+		AllowFailure bool
+		Value        *big.Int
+		CallData     []byte
+	}{{common.HexToAddress("0x01"), true, big.NewInt(5), []byte{0x18, 0x16, 0x0d, 0xdd}}}
+	packed, err := abi.Arguments{{Type: call3ValueListType}}.Pack(calls)
+	require.NoError(t, err)
+	input := append(common.FromHex(Aggregate3ValueMethod), packed...)
+
+	registry, err := NewMethodRegistry("function totalSupply() view returns (uint256 supply)")
+	require.NoError(t, err)
+	decoded, err := DecodeAggregateCallData(input, registry)
+	require.NoError(t, err)
+	require.Len(t, decoded.Calls, 1)
+	assert.Equal(t, big.NewInt(5), decoded.Calls[0].Value)
+	assert.True(t, decoded.Calls[0].AllowFailure)
+
+	output, err := abi.Arguments{{Type: returnListType}}.Pack([]AggregateReturn{
+		{Success: true, Data: common.LeftPadBytes([]byte{42}, 32)},
+	})
+	require.NoError(t, err)
+	_, err = decoded.DecodeOutput(output)
+	require.NoError(t, err)
+	result := decoded.Calls[0].Result
+	require.NotNil(t, result)
+	assert.Equal(t, StatusSuccess, result.Status)
+	assert.Equal(t, int64(42), result.Named["supply"].(*BigIntJSONString).ToBigInt().Int64())
+
+	_, err = DecodeAggregateCallData([]byte{1, 2, 3, 4}, nil)
+	assert.Error(t, err)
+}
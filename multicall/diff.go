@@ -0,0 +1,279 @@
+package multicall
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffKind is the kind of difference of a call between two Results
+type DiffKind int
+
+const (
+	// DiffAdded is a call only in the new Result
+	DiffAdded DiffKind = iota
+	// DiffRemoved is a call only in the old Result
+	DiffRemoved
+	// DiffStatus is a call whose status changed, e.g. from success to failed
+	DiffStatus
+	// DiffValues is a call whose return values changed
+	DiffValues
+	// DiffDecode is a call whose return data started or stopped failing to
+	// decode, or fails with a different error
+	DiffDecode
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffStatus:
+		return "status"
+	case DiffValues:
+		return "values"
+	case DiffDecode:
+		return "decode"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// ValueChange is a single changed return value
+type ValueChange struct {
+	// Path locates the value within the return values, e.g. "[0]",
+	// "[1].reserve0" or "[2][3]". It is empty for the return data of calls
+	// without decoded values.
+	Path string
+	// Old and New are nil when a list element or tuple field exists on one
+	// side only
+	Old interface{}
+	New interface{}
+	// Delta is New - Old when both are integers
+	Delta *big.Int
+	// RelativeDelta is Delta / |Old|, nil when Old is not an integer or 0
+	RelativeDelta *big.Float
+}
+
+// CallDiff is the difference of a single call between two Results
+type CallDiff struct {
+	ID   string
+	Kind DiffKind
+	// Old and New are nil for added and removed calls respectively
+	Old *CallResult
+	New *CallResult
+	// Changes lists the changed values of DiffValues
+	Changes []ValueChange
+}
+
+// ResultDiff is the difference between two Results, with calls sorted by
+// ID
+type ResultDiff struct {
+	OldBlockNumber uint64
+	NewBlockNumber uint64
+	Calls          []CallDiff
+}
+
+// Diff compares the calls of a with those of b. Integers are compared
+// numerically, tuples field by field and lists element by element. Calls
+// that were not decoded, e.g. by CallRaw, are compared by their return
+// data. A nil Result has no calls.
+func Diff(a, b *Result) *ResultDiff {
+	if a == nil {
+		a = &Result{}
+	}
+	if b == nil {
+		b = &Result{}
+	}
+	diff := &ResultDiff{OldBlockNumber: a.BlockNumber, NewBlockNumber: b.BlockNumber}
+	ids := make([]string, 0, len(a.Calls)+len(b.Calls))
+	for id := range a.Calls {
+		ids = append(ids, id)
+	}
+	for id := range b.Calls {
+		if _, ok := a.Calls[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		before, inA := a.Calls[id]
+		after, inB := b.Calls[id]
+		switch {
+		case !inA:
+			diff.Calls = append(diff.Calls, CallDiff{ID: id, Kind: DiffAdded, New: &after})
+		case !inB:
+			diff.Calls = append(diff.Calls, CallDiff{ID: id, Kind: DiffRemoved, Old: &before})
+		case before.Success != after.Success || before.Status != after.Status:
+			diff.Calls = append(diff.Calls, CallDiff{ID: id, Kind: DiffStatus, Old: &before, New: &after})
+		case errorText(before.DecodeError) != errorText(after.DecodeError):
+			diff.Calls = append(diff.Calls, CallDiff{ID: id, Kind: DiffDecode, Old: &before, New: &after})
+		default:
+			if changes := diffCallValues(before, after); len(changes) > 0 {
+				diff.Calls = append(diff.Calls, CallDiff{ID: id, Kind: DiffValues, Old: &before, New: &after, Changes: changes})
+			}
+		}
+	}
+	return diff
+}
+
+// Empty reports whether no call differs
+func (d *ResultDiff) Empty() bool {
+	return len(d.Calls) == 0
+}
+
+// String formats the diff with one line per added, removed or status
+// changed call and per changed value
+func (d *ResultDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "block %d -> %d\n", d.OldBlockNumber, d.NewBlockNumber)
+	for _, call := range d.Calls {
+		switch call.Kind {
+		case DiffAdded:
+			fmt.Fprintf(&b, "+ %s: %s\n", call.ID, formatCallValues(call.New))
+		case DiffRemoved:
+			fmt.Fprintf(&b, "- %s: %s\n", call.ID, formatCallValues(call.Old))
+		case DiffStatus:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", call.ID, call.Old.Status, call.New.Status)
+		case DiffDecode:
+			fmt.Fprintf(&b, "~ %s: decode error %s -> %s\n", call.ID, formatDiffValue(errorText(call.Old.DecodeError)), formatDiffValue(errorText(call.New.DecodeError)))
+		case DiffValues:
+			for _, change := range call.Changes {
+				fmt.Fprintf(&b, "~ %s%s: %s -> %s", call.ID, change.Path, formatDiffValue(change.Old), formatDiffValue(change.New))
+				if change.Delta != nil {
+					fmt.Fprintf(&b, " (%+d", change.Delta)
+					if change.RelativeDelta != nil {
+						percent := new(big.Float).Mul(change.RelativeDelta, big.NewFloat(100))
+						fmt.Fprintf(&b, ", %+.4g%%", percent)
+					}
+					b.WriteString(")")
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// errorText is the message of err, nil when there is no error
+func errorText(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
+
+func diffCallValues(before, after CallResult) []ValueChange {
+	if len(before.Decoded) == 0 && len(after.Decoded) == 0 {
+		if bytes.Equal(before.Raw, after.Raw) {
+			return nil
+		}
+		return []ValueChange{{Old: before.Raw, New: after.Raw}}
+	}
+	return diffValues("", before.Decoded, after.Decoded, nil)
+}
+
+// diffValues appends the changes between before and after at path to
+// changes
+func diffValues(path string, before, after interface{}, changes []ValueChange) []ValueChange {
+	if oldInt, ok := asInteger(before); ok {
+		newInt, ok := asInteger(after)
+		if !ok {
+			return append(changes, ValueChange{Path: path, Old: before, New: after})
+		}
+		if oldInt.Cmp(newInt) == 0 {
+			return changes
+		}
+		change := ValueChange{Path: path, Old: before, New: after, Delta: new(big.Int).Sub(newInt, oldInt)}
+		if oldInt.Sign() != 0 {
+			change.RelativeDelta = new(big.Float).Quo(new(big.Float).SetInt(change.Delta), new(big.Float).SetInt(new(big.Int).Abs(oldInt)))
+		}
+		return append(changes, change)
+	}
+
+	switch oldValue := before.(type) {
+	case []interface{}:
+		newValue, ok := after.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(oldValue) || i < len(newValue); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(newValue):
+				changes = append(changes, ValueChange{Path: elemPath, Old: oldValue[i]})
+			case i >= len(oldValue):
+				changes = append(changes, ValueChange{Path: elemPath, New: newValue[i]})
+			default:
+				changes = diffValues(elemPath, oldValue[i], newValue[i], changes)
+			}
+		}
+		return changes
+	case map[string]interface{}:
+		newValue, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(oldValue)+len(newValue))
+		for key := range oldValue {
+			keys = append(keys, key)
+		}
+		for key := range newValue {
+			if _, ok := oldValue[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			changes = diffValues(path+"."+key, oldValue[key], newValue[key], changes)
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		changes = append(changes, ValueChange{Path: path, Old: before, New: after})
+	}
+	return changes
+}
+
+func formatCallValues(call *CallResult) string {
+	if !call.Success {
+		return call.Status.String()
+	}
+	if len(call.Decoded) == 0 {
+		return formatDiffValue(call.Raw)
+	}
+	return formatDiffValue(call.Decoded)
+}
+
+func formatDiffValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<none>"
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = formatDiffValue(elem)
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = key + ":" + formatDiffValue(v[key])
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	}
+	return fmt.Sprint(value)
+}
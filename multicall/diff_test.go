@@ -0,0 +1,119 @@
+package multicall
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigJSON(n int64) *BigIntJSONString {
+	return (*BigIntJSONString)(big.NewInt(n))
+}
+
+func TestDiff(t *testing.T) {
+	a := &Result{
+		BlockNumber: 100,
+		Calls: map[string]CallResult{
+			"balance":  {Success: true, Decoded: []interface{}{bigJSON(200)}},
+			"reserves": {Success: true, Decoded: []interface{}{map[string]interface{}{"reserve0": bigJSON(100), "reserve1": bigJSON(50)}, []interface{}{uint8(1), uint8(2)}}},
+			"owner":    {Success: true, Decoded: []interface{}{"0x01"}},
+			"paused":   {Success: true, Decoded: []interface{}{false}},
+			"removed":  {Success: true, Decoded: []interface{}{true}},
+			"raw":      {Success: true, Raw: []byte{1}},
+		},
+	}
+	b := &Result{
+		BlockNumber: 101,
+		Calls: map[string]CallResult{
+			"balance":  {Success: true, Decoded: []interface{}{bigJSON(150)}},
+			"reserves": {Success: true, Decoded: []interface{}{map[string]interface{}{"reserve0": bigJSON(100), "reserve1": bigJSON(75)}, []interface{}{uint8(1), uint8(2), uint8(3)}}},
+			"owner":    {Success: true, Decoded: []interface{}{"0x01"}},
+			"paused":   {Success: false, Status: StatusFailed},
+			"added":    {Success: true, Decoded: []interface{}{bigJSON(1)}},
+			"raw":      {Success: true, Raw: []byte{2}},
+		},
+	}
+
+	diff := Diff(a, b)
+	require.Len(t, diff.Calls, 6)
+	ids := make([]string, len(diff.Calls))
+	kinds := make([]DiffKind, len(diff.Calls))
+	for i, call := range diff.Calls {
+		ids[i], kinds[i] = call.ID, call.Kind
+	}
+	assert.Equal(t, []string{"added", "balance", "paused", "raw", "removed", "reserves"}, ids)
+	assert.Equal(t, []DiffKind{DiffAdded, DiffValues, DiffStatus, DiffValues, DiffRemoved, DiffValues}, kinds)
+
+	balance := diff.Calls[1].Changes
+	require.Len(t, balance, 1)
+	assert.Equal(t, "[0]", balance[0].Path)
+	assert.Equal(t, int64(-50), balance[0].Delta.Int64())
+	relative, _ := balance[0].RelativeDelta.Float64()
+	assert.Equal(t, -0.25, relative)
+
+	reserves := diff.Calls[5].Changes
+	require.Len(t, reserves, 2)
+	assert.Equal(t, "[0].reserve1", reserves[0].Path)
+	assert.Equal(t, int64(25), reserves[0].Delta.Int64())
+	assert.Equal(t, ValueChange{Path: "[1][2]", New: uint8(3)}, reserves[1])
+
+	assert.Equal(t, `block 100 -> 101
++ added: [1]
+~ balance[0]: 200 -> 150 (-50, -25%)
+~ paused: success -> failed
+~ raw: 0x01 -> 0x02
+- removed: [true]
+~ reserves[0].reserve1: 50 -> 75 (+25, +50%)
+~ reserves[1][2]: <none> -> 3
+`, diff.String())
+
+	assert.True(t, Diff(a, a).Empty())
+}
+
+func TestDiffNil(t *testing.T) {
+	res := &Result{
+		BlockNumber: 100,
+		Calls:       map[string]CallResult{"balance": {Success: true, Decoded: []interface{}{bigJSON(200)}}},
+	}
+
+	diff := Diff(nil, res)
+	require.Len(t, diff.Calls, 1)
+	assert.Equal(t, DiffAdded, diff.Calls[0].Kind)
+	assert.Equal(t, uint64(0), diff.OldBlockNumber)
+
+	diff = Diff(res, nil)
+	require.Len(t, diff.Calls, 1)
+	assert.Equal(t, DiffRemoved, diff.Calls[0].Kind)
+
+	assert.True(t, Diff(nil, nil).Empty())
+}
+
+func TestDiffDecodeError(t *testing.T) {
+	a := &Result{
+		BlockNumber: 100,
+		Calls: map[string]CallResult{
+			"name":   {Success: true, Decoded: []interface{}{"token"}},
+			"symbol": {Success: true, DecodeError: errors.New("abi: cannot marshal")},
+		},
+	}
+	b := &Result{
+		BlockNumber: 101,
+		Calls: map[string]CallResult{
+			"name":   {Success: true, DecodeError: errors.New("abi: improperly formatted output")},
+			"symbol": {Success: true, DecodeError: errors.New("abi: cannot marshal")},
+		},
+	}
+
+	diff := Diff(a, b)
+	require.Len(t, diff.Calls, 1)
+	assert.Equal(t, "name", diff.Calls[0].ID)
+	assert.Equal(t, DiffDecode, diff.Calls[0].Kind)
+	assert.Equal(t, `block 100 -> 101
+~ name: decode error <none> -> abi: improperly formatted output
+`, diff.String())
+
+	assert.Equal(t, DiffDecode, Diff(b, a).Calls[0].Kind)
+}
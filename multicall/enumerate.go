@@ -0,0 +1,193 @@
+package multicall
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+type enumerateConfig struct {
+	pageSize    int
+	concurrency int
+	offset      uint64
+	limit       uint64
+	callOpts    []CallOption
+}
+
+type EnumerateOption func(*enumerateConfig)
+
+// PageSize sets the number of items read per aggregate call, DefaultChunkSize
+// by default
+func PageSize(size int) EnumerateOption {
+	return func(c *enumerateConfig) {
+		c.pageSize = size
+	}
+}
+
+// Concurrency sets the number of aggregate calls in flight, 1 by default
+func Concurrency(n int) EnumerateOption {
+	return func(c *enumerateConfig) {
+		c.concurrency = n
+	}
+}
+
+// StartAt skips the items before offset, e.g. to resume an enumeration
+// that failed
+func StartAt(offset uint64) EnumerateOption {
+	return func(c *enumerateConfig) {
+		c.offset = offset
+	}
+}
+
+// Limit reads at most n items
+func Limit(n uint64) EnumerateOption {
+	return func(c *enumerateConfig) {
+		c.limit = n
+	}
+}
+
+// WithCallOptions sets the CallOptions of the length call and of every
+// page, so that they all run with the same sender, gas and overrides.
+// Computed fields are not supported.
+func WithCallOptions(opts ...CallOption) EnumerateOption {
+	return func(c *enumerateConfig) {
+		c.callOpts = append(c.callOpts, opts...)
+	}
+}
+
+// Enumeration is the outcome of Enumerate. Items holds the items from
+// Offset on, in index order.
+type Enumeration[T any] struct {
+	BlockNumber uint64
+	Length      uint64
+	Offset      uint64
+	Items       []T
+}
+
+// Enumerate reads an on-chain collection such as allPairs(i) or
+// tokenByIndex(i). It reads the collection size with length, then calls
+// item for every index and reads the items in pages at the block length
+// ran at. extract converts the result of a single item, so the extractors
+// generated by multicall-gen can be passed as is.
+//
+// If a page fails the items of the pages before it are returned together
+// with the error; pass StartAt(Offset+len(Items)) and a BlockNumberRef of
+// BlockNumber to resume.
+func Enumerate[T any](mc Multicall, length ViewCall, item func(index uint64) ViewCall, extract func(res *Result, id string) (T, error), block ethrpc.BlockRef, opts ...EnumerateOption) (*Enumeration[T], error) {
+	config := enumerateConfig{pageSize: DefaultChunkSize, concurrency: 1}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.pageSize <= 0 {
+		config.pageSize = DefaultChunkSize
+	}
+	if config.concurrency <= 0 {
+		config.concurrency = 1
+	}
+	if err := rejectComputed(config.callOpts, "Enumerate"); err != nil {
+		return nil, err
+	}
+
+	res, err := mc.Call(ViewCalls{length}, block, config.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	values, err := res.Values(length.id)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("collection length: call %s returned %d values", length.id, len(values))
+	}
+	total, err := AsBigInt(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("collection length: %w", err)
+	}
+	if !total.IsUint64() {
+		return nil, fmt.Errorf("invalid collection length %s", total)
+	}
+	block = block.Pin(res.BlockNumber)
+
+	enumeration := &Enumeration[T]{
+		BlockNumber: res.BlockNumber,
+		Length:      total.Uint64(),
+		Offset:      config.offset,
+	}
+	end := enumeration.Length
+	if config.limit > 0 && config.offset+config.limit < end {
+		end = config.offset + config.limit
+	}
+	if enumeration.Offset > end {
+		enumeration.Offset = end
+	}
+
+	pageSize := uint64(config.pageSize)
+	pages := int((end - enumeration.Offset + pageSize - 1) / pageSize)
+	items := make([][]T, pages)
+	errs := make([]error, pages)
+
+	var mu sync.Mutex
+	failed := false
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.concurrency)
+	for page := 0; page < pages; page++ {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		from := enumeration.Offset + uint64(page)*pageSize
+		to := from + pageSize
+		if to > end {
+			to = end
+		}
+		wg.Add(1)
+		go func(page int, from, to uint64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			items[page], errs[page] = enumeratePage(mc, item, extract, block, from, to, config.callOpts)
+			if errs[page] != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(page, from, to)
+	}
+	wg.Wait()
+
+	// pages skipped after a failure all come after the failed page
+	for page := range items {
+		if errs[page] != nil {
+			from := enumeration.Offset + uint64(len(enumeration.Items))
+			return enumeration, fmt.Errorf("enumerating from index %d: %w", from, errs[page])
+		}
+		enumeration.Items = append(enumeration.Items, items[page]...)
+	}
+	return enumeration, nil
+}
+
+func enumeratePage[T any](mc Multicall, item func(index uint64) ViewCall, extract func(res *Result, id string) (T, error), block ethrpc.BlockRef, from, to uint64, opts []CallOption) ([]T, error) {
+	calls := make(ViewCalls, 0, to-from)
+	for index := from; index < to; index++ {
+		call := item(index)
+		call.id = strconv.FormatUint(index, 10)
+		calls = append(calls, call)
+	}
+	res, err := mc.Call(calls, block, opts...)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]T, len(calls))
+	for i, call := range calls {
+		if items[i], err = extract(res, call.id); err != nil {
+			return nil, fmt.Errorf("item %s: %w", call.id, err)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,132 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respondCollection answers length() with n and item(i) with i*i, failing
+// the item at index fail
+func respondCollection(n, fail int64) func(target [20]byte, callData []byte) AggregateReturn {
+	return func(target [20]byte, callData []byte) AggregateReturn {
+		if len(callData) == 4 {
+			return AggregateReturn{Success: true, Data: common.LeftPadBytes(big.NewInt(n).Bytes(), 32)}
+		}
+		index := new(big.Int).SetBytes(callData[4:]).Int64()
+		if index == fail {
+			return AggregateReturn{}
+		}
+		return AggregateReturn{Success: true, Data: common.LeftPadBytes(big.NewInt(index*index).Bytes(), 32)}
+	}
+}
+
+func enumerateSquares(mc Multicall, opts ...EnumerateOption) (*Enumeration[int64], error) {
+	return Enumerate(mc,
+		NewViewCall("length", factoryAddress, "length()(uint256)", nil),
+		func(index uint64) ViewCall {
+			return NewViewCall(fmt.Sprint(index), factoryAddress, "item(uint256)(uint256)", []interface{}{index})
+		},
+		func(res *Result, id string) (int64, error) {
+			values, err := res.Values(id)
+			if err != nil {
+				return 0, err
+			}
+			value, err := AsBigInt(values[0])
+			if err != nil {
+				return 0, err
+			}
+			return value.Int64(), nil
+		},
+		ethrpc.LatestBlock,
+		opts...,
+	)
+}
+
+func TestEnumerate(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: respondCollection(25, -1)}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	enumeration, err := enumerateSquares(mc, PageSize(10), Concurrency(3))
+	require.NoError(t, err)
+	assert.Equal(t, 4, eth.requests)
+	assert.Equal(t, ethrpc.BlockNumberRef(42), eth.params[1])
+	assert.Equal(t, uint64(42), enumeration.BlockNumber)
+	assert.Equal(t, uint64(25), enumeration.Length)
+	require.Len(t, enumeration.Items, 25)
+	for i, item := range enumeration.Items {
+		assert.Equal(t, int64(i*i), item)
+	}
+
+	enumeration, err = enumerateSquares(mc, StartAt(20), Limit(3))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20), enumeration.Offset)
+	assert.Equal(t, []int64{400, 441, 484}, enumeration.Items)
+
+	enumeration, err = enumerateSquares(mc, StartAt(30))
+	require.NoError(t, err)
+	assert.Empty(t, enumeration.Items)
+}
+
+func TestEnumerateResume(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: respondCollection(25, 15)}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	enumeration, err := enumerateSquares(mc, PageSize(10), Concurrency(2))
+	assert.Error(t, err)
+	assert.Len(t, enumeration.Items, 10)
+
+	eth.respond = respondCollection(25, -1)
+	resumed, err := enumerateSquares(mc, PageSize(10), StartAt(enumeration.Offset+uint64(len(enumeration.Items))))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), resumed.Offset)
+	assert.Len(t, resumed.Items, 15)
+	assert.Equal(t, int64(100), resumed.Items[0])
+}
+
+// senderETH records the sender of every eth_call
+type senderETH struct {
+	*fakeETH
+	senders []string
+}
+
+func (s *senderETH) SendRequest(result interface{}, method string, params ...interface{}) error {
+	s.senders = append(s.senders, params[0].(ethrpc.CallMsg).From)
+	return s.fakeETH.SendRequest(result, method, params...)
+}
+
+func TestEnumerateCallOptions(t *testing.T) {
+	eth := &senderETH{fakeETH: &fakeETH{blockNumber: 42, respond: respondCollection(5, -1)}}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	sender := "0x0000000000000000000000000000000000001234"
+	_, err = enumerateSquares(mc, PageSize(2), WithCallOptions(From(sender)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{sender, sender, sender, sender}, eth.senders)
+
+	_, err = enumerateSquares(mc, WithCallOptions(Compute("sum", "{0} + {1}")))
+	assert.Error(t, err)
+}
+
+func TestEnumerateLengthWithoutValue(t *testing.T) {
+	mc, err := New(&fakeETH{blockNumber: 42, respond: respondCollection(5, -1)})
+	require.NoError(t, err)
+
+	_, err = Enumerate(mc,
+		NewViewCall("length", factoryAddress, "allPairsLength()", nil),
+		func(index uint64) ViewCall {
+			return NewViewCall(fmt.Sprint(index), factoryAddress, "item(uint256)(uint256)", []interface{}{index})
+		},
+		func(res *Result, id string) (int64, error) { return 0, nil },
+		ethrpc.LatestBlock,
+	)
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/ethrpc/jsonrpc"
+)
+
+// CallExplanation describes how a single call is encoded
+type CallExplanation struct {
+	ID     string
+	Target string
+	// Signature is the canonical signature, e.g. balanceOf(address), and
+	// the text hashed into the selector whatever names, spaces or type
+	// aliases the method string contains
+	Signature string
+	Selector  string
+	// Arguments are the ABI encoded arguments following the selector
+	Arguments   string
+	CallData    string
+	ReturnTypes []string
+}
+
+// Explanation describes the aggregate call Call would send for a batch
+type Explanation struct {
+	Contract string
+	Calls    []CallExplanation
+	// CallData is the complete calldata of the aggregate call
+	CallData string
+	// CallDataSize is the size of CallData in bytes
+	CallDataSize int
+	// Request is the eth_call JSON-RPC request. Its ID differs from the one
+	// of the request Call sends.
+	Request *jsonrpc.JSONRPCRequest
+}
+
+// Explain encodes calls the way Call does for block and describes the
+// result without sending anything. The calls of Config.Chain come first.
+// All calls are explained as a single aggregate call: an adaptive
+// Multicall may split them into chunks and lower the gas when it sends
+// them, so its requests differ from the one explained.
+func (mc multicall) Explain(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Explanation, error) {
+	calls = mc.withChainCalls(calls)
+	explanation := &Explanation{
+		Contract: mc.config.MulticallAddress,
+		Calls:    make([]CallExplanation, len(calls)),
+	}
+	for i, call := range calls {
+		callExplanation, err := call.explain()
+		if err != nil {
+			return nil, fmt.Errorf("call %s: %w", call.id, err)
+		}
+		explanation.Calls[i] = *callExplanation
+	}
+
+	request, err := mc.prepare(calls, opts)
+	if err != nil {
+		return nil, err
+	}
+	params, err := mc.callParams(request, block, opts)
+	if err != nil {
+		return nil, err
+	}
+	explanation.CallData = params[0].(ethrpc.CallMsg).Data
+	explanation.CallDataSize = len(strings.TrimPrefix(explanation.CallData, "0x")) / 2
+	explanation.Request = jsonrpc.BuildRequest(ethrpc.ETH_Call, params)
+	return explanation, nil
+}
+
+func (call ViewCall) explain() (*CallExplanation, error) {
+	sig, err := call.signature()
+	if err != nil {
+		return nil, err
+	}
+	selector, err := call.methodCallData()
+	if err != nil {
+		return nil, err
+	}
+	args, err := call.argsCallData()
+	if err != nil {
+		return nil, err
+	}
+	return &CallExplanation{
+		ID:          call.id,
+		Target:      call.target,
+		Signature:   fmt.Sprintf("%s(%s)", sig.name, strings.Join(call.argumentTypes(), ",")),
+		Selector:    "0x" + hex.EncodeToString(selector),
+		Arguments:   "0x" + hex.EncodeToString(args),
+		CallData:    "0x" + hex.EncodeToString(append(selector, args...)),
+		ReturnTypes: call.returnTypes(),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	eth := &fakeETH{respond: echoArgument}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	calls := ViewCalls{
+		NewViewCall("spaced", "0x0000000000000000000000000000000000000001", "balanceOf(address, uint64)(int256)", []interface{}{"0x0000000000000000000000000000000000001234", uint64(12)}),
+		NewViewCall("fragment", "0x0000000000000000000000000000000000000002", "function balanceOf(address owner) view returns (uint256)", []interface{}{"0x0000000000000000000000000000000000001234"}),
+	}
+	explanation, err := mc.Explain(calls, ethrpc.BlockNumberRef(100))
+	require.NoError(t, err)
+	assert.Equal(t, 0, eth.requests)
+	assert.Equal(t, MainnetAddress, explanation.Contract)
+
+	spaced := explanation.Calls[0]
+	assert.Equal(t, "balanceOf(address,uint64)", spaced.Signature)
+	assert.Equal(t, "0x8089452e", spaced.Selector)
+	assert.Equal(t, []string{"int256"}, spaced.ReturnTypes)
+	assert.Equal(t, spaced.Selector+spaced.Arguments[2:], spaced.CallData)
+
+	fragment := explanation.Calls[1]
+	assert.Equal(t, "balanceOf(address)", fragment.Signature)
+	assert.Equal(t, "0x70a08231", fragment.Selector)
+
+	aggregateCalls, err := calls.aggregateCalls()
+	require.NoError(t, err)
+	callData, err := StrictAggregator(false).EncodeCalls(aggregateCalls)
+	require.NoError(t, err)
+	assert.Equal(t, "0x"+hex.EncodeToString(callData), explanation.CallData)
+	assert.Equal(t, len(callData), explanation.CallDataSize)
+
+	assert.Equal(t, ethrpc.ETH_Call, explanation.Request.Method)
+	params := explanation.Request.Params.([]interface{})
+	require.Len(t, params, 2)
+	assert.Equal(t, ethrpc.CallMsg{To: MainnetAddress, Data: explanation.CallData, Gas: "0x400000000"}, params[0])
+	assert.Equal(t, ethrpc.BlockNumberRef(100), params[1])
+}
+
+func TestExplainCheckCode(t *testing.T) {
+	mc, err := New(&fakeETH{respond: echoArgument}, CheckCode())
+	require.NoError(t, err)
+
+	explanation, err := mc.Explain(numberedCalls(2), ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Len(t, explanation.Calls, 2)
+	params := explanation.Request.Params.([]interface{})
+	require.Len(t, params, 3)
+	assert.Equal(t, codeSizeOverride(), params[2])
+}
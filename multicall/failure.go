@@ -0,0 +1,284 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/howjmay/multicall/utils"
+)
+
+// ErrCallSucceeded is returned by TraceFailure when the call does not fail
+var ErrCallSucceeded = errors.New("call did not fail")
+
+// Failure describes where a call failed
+type Failure struct {
+	ID string
+	// Path is the call target followed by every contract it called on the
+	// way to the one that failed
+	Path []string
+	// Op is the opcode the failing contract stopped at, e.g. REVERT, INVALID
+	// or the opcode that ran out of gas
+	Op string
+	PC uint64
+	// Error is the error the tracer reports for the failing contract
+	Error string
+	// RevertData is the data the call reverted with
+	RevertData []byte
+	// Reason is the reason string of RevertData when it is an Error(string)
+	Reason string
+	// Trace are the calls made, as trace_call reports them
+	Trace []types.Trace
+	// VMTrace is the instruction trace of the call. Code is only set with
+	// ParityTracer.
+	VMTrace *types.VMTrace
+}
+
+// failureFrame is a call frame of a traced call and the last instruction
+// it ran
+type failureFrame struct {
+	address string
+	op      string
+	pc      uint64
+	err     string
+	calls   []*failureFrame
+}
+
+// TraceFailure re-executes call on its own at block with an instruction
+// trace and locates where it failed. The failing contract is found by
+// following the last failed call of every frame, which is where a revert
+// that bubbled up started. Unlike in a batch, From sets the msg.sender the
+// call sees.
+func (mc multicall) TraceFailure(call ViewCall, block ethrpc.BlockRef, tracer Tracer, opts ...CallOption) (*Failure, error) {
+	callData, err := call.callData()
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", call.id, err)
+	}
+	config := &callConfig{
+		msg: ethrpc.CallMsg{
+			To:   call.target,
+			Data: "0x" + hex.EncodeToString(callData),
+			Gas:  mc.config.Gas,
+		},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	config.msg.Value = quantity(config.value)
+
+	failure := &Failure{ID: call.id}
+	var root *failureFrame
+	var output string
+	switch tracer {
+	case ParityTracer:
+		if config.state != nil || config.blockOverrides != nil {
+			return nil, errors.New("trace_call does not support state or block overrides")
+		}
+		replay, err := mc.eth.TraceCall(config.msg, block, "trace", "vmTrace")
+		if err != nil {
+			return nil, err
+		}
+		if root, err = parityFailureFrames(replay.Trace, replay.VMTrace); err != nil {
+			return nil, err
+		}
+		failure.Trace, failure.VMTrace, output = replay.Trace, replay.VMTrace, replay.Output
+	case GethTracer:
+		var result types.ExecutionResult
+		err := mc.eth.DebugTraceCall(&result, config.msg, block, ethrpc.TraceConfig{
+			DisableStorage: true,
+			StateOverrides: config.state,
+			BlockOverrides: config.blockOverrides,
+		})
+		if err != nil {
+			return nil, err
+		}
+		walker := &structLogWalker{logs: result.StructLogs}
+		root = &failureFrame{address: call.target}
+		walker.traces = []types.Trace{callTrace("call", config.msg.From, call.target, []int{})}
+		failure.VMTrace = walker.walk(root, 0, 1)
+		if !result.Failed {
+			root.err = ""
+		} else if root.err == "" {
+			root.err = "execution failed"
+		}
+		if root.err != "" {
+			walker.traces[0].Error = &root.err
+		}
+		failure.Trace = walker.traces
+		output = result.ReturnValue
+	default:
+		return nil, fmt.Errorf("unknown tracer %d", tracer)
+	}
+	if root.err == "" {
+		return nil, ErrCallSucceeded
+	}
+
+	failure.locate(root)
+	if failure.RevertData, err = hex.DecodeString(strings.TrimPrefix(output, "0x")); err != nil {
+		return nil, fmt.Errorf("revert data %s: %w", output, err)
+	}
+	if reason, err := abi.UnpackRevert(failure.RevertData); err == nil {
+		failure.Reason = reason
+	}
+	return failure, nil
+}
+
+// locate descends from the failed frame into the last failed call of every
+// frame
+func (f *Failure) locate(frame *failureFrame) {
+	for {
+		f.Path = append(f.Path, frame.address)
+		var next *failureFrame
+		for _, call := range frame.calls {
+			if call.err != "" {
+				next = call
+			}
+		}
+		if next == nil {
+			break
+		}
+		frame = next
+	}
+	f.Op, f.PC, f.Error = frame.op, frame.pc, frame.err
+}
+
+// parityFailureFrames builds the frames of trace_call traces, which list
+// every call before the calls it made, and takes their last instruction
+// from vmTrace
+func parityFailureFrames(traces []types.Trace, vmTrace *types.VMTrace) (*failureFrame, error) {
+	frames := make(map[string]*failureFrame, len(traces))
+	var root *failureFrame
+	for _, trace := range traces {
+		frame := &failureFrame{}
+		if trace.Action.To != nil {
+			frame.address = *trace.Action.To
+		}
+		if trace.Error != nil {
+			frame.err = *trace.Error
+		}
+		frames[fmt.Sprint(trace.TraceAddress)] = frame
+		if len(trace.TraceAddress) == 0 {
+			root = frame
+			continue
+		}
+		parent, ok := frames[fmt.Sprint(trace.TraceAddress[:len(trace.TraceAddress)-1])]
+		if !ok {
+			return nil, fmt.Errorf("trace %v comes before its caller", trace.TraceAddress)
+		}
+		parent.calls = append(parent.calls, frame)
+	}
+	if root == nil {
+		return nil, errors.New("trace_call returned no root trace")
+	}
+	if vmTrace != nil {
+		root.setVMTrace(vmTrace)
+	}
+	return root, nil
+}
+
+// setVMTrace takes the last instruction of the frame from vmTrace, whose
+// subtraces follow the calls of the frame in order
+func (frame *failureFrame) setVMTrace(vmTrace *types.VMTrace) {
+	if n := len(vmTrace.Ops); n > 0 {
+		last := vmTrace.Ops[n-1]
+		frame.pc = uint64(last.Pc)
+		code := common.FromHex(vmTrace.Code)
+		if last.Pc < len(code) {
+			frame.op = vm.OpCode(code[last.Pc]).String()
+		}
+	}
+	i := 0
+	for _, op := range vmTrace.Ops {
+		if op.Sub == nil {
+			continue
+		}
+		if i < len(frame.calls) {
+			frame.calls[i].setVMTrace(op.Sub)
+		}
+		i++
+	}
+}
+
+// structLogWalker turns geth struct logs into frames, call traces and an
+// instruction trace
+type structLogWalker struct {
+	logs   []types.StructLog
+	next   int
+	traces []types.Trace
+}
+
+// walk consumes the struct logs of frame, running at depth and traced at
+// traces[trace], and returns its instruction trace
+func (w *structLogWalker) walk(frame *failureFrame, trace int, depth int) *types.VMTrace {
+	vmTrace := &types.VMTrace{}
+	for w.next < len(w.logs) && w.logs[w.next].Depth == depth {
+		log := w.logs[w.next]
+		w.next++
+		op := types.VMTraceOp{Cost: int(log.GasCost), Pc: int(log.Pc)}
+		if log.Gas > log.GasCost {
+			op.Ex.Used = int(log.Gas - log.GasCost)
+		}
+		frame.op, frame.pc, frame.err = log.Op, log.Pc, log.Error
+
+		if w.next < len(w.logs) && w.logs[w.next].Depth == depth+1 {
+			call := &failureFrame{address: callTarget(log)}
+			frame.calls = append(frame.calls, call)
+			traceAddress := append(append([]int{}, w.traces[trace].TraceAddress...), w.traces[trace].Subtraces)
+			w.traces[trace].Subtraces++
+			callType := strings.ToLower(log.Op)
+			if strings.HasPrefix(log.Op, "CREATE") {
+				callType = "create"
+			}
+			w.traces = append(w.traces, callTrace(callType, frame.address, call.address, traceAddress))
+			index := len(w.traces) - 1
+			op.Sub = w.walk(call, index, depth+1)
+			if call.err != "" {
+				w.traces[index].Error = &call.err
+			}
+		}
+		vmTrace.Ops = append(vmTrace.Ops, op)
+	}
+
+	switch {
+	case frame.err != "":
+	case frame.op == "REVERT":
+		frame.err = "execution reverted"
+	case frame.op == "INVALID":
+		frame.err = "invalid opcode"
+	}
+	return vmTrace
+}
+
+func callTrace(callType, from, to string, traceAddress []int) types.Trace {
+	trace := types.Trace{
+		Type:         "call",
+		TraceAddress: traceAddress,
+		Action:       types.TraceAction{CallType: &callType, From: &from},
+	}
+	if callType == "create" {
+		trace.Type = "create"
+		trace.Action.CallType = nil
+	} else {
+		trace.Action.To = &to
+	}
+	return trace
+}
+
+// callTarget is the address called by a CALL, CALLCODE, DELEGATECALL or
+// STATICCALL, the second item from the top of their stack
+func callTarget(log types.StructLog) string {
+	if !strings.HasSuffix(log.Op, "CALL") && log.Op != "CALLCODE" || len(log.Stack) < 2 {
+		return ""
+	}
+	address, err := utils.HexToBigInt(log.Stack[len(log.Stack)-2])
+	if err != nil {
+		return ""
+	}
+	return common.BigToAddress(address).Hex()
+}
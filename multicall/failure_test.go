@@ -0,0 +1,90 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func revertData(t *testing.T, reason string) string {
+	stringType, err := abi.NewType("string", "", nil)
+	require.NoError(t, err)
+	data, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	require.NoError(t, err)
+	return "0x08c379a0" + hex.EncodeToString(data)
+}
+
+func TestTraceFailure(t *testing.T) {
+	target := "0x0000000000000000000000000000000000000001"
+	oracle := "0x00000000000000000000000000000000000000AA"
+	output := revertData(t, "stale price")
+	eth := &tracingETH{
+		fakeETH: &fakeETH{},
+		replay: types.TransactionReplay{
+			Output: output,
+			Trace: []types.Trace{
+				{Action: types.TraceAction{To: stringPtr(target)}, Error: stringPtr("Reverted")},
+				{TraceAddress: []int{0}, Action: types.TraceAction{To: stringPtr(oracle)}, Error: stringPtr("Reverted")},
+			},
+			// the target calls the oracle and reverts at pc 3, the oracle
+			// reverts at pc 2
+			VMTrace: &types.VMTrace{Code: "0x6000fafd", Ops: []types.VMTraceOp{
+				{Pc: 0},
+				{Pc: 2, Sub: &types.VMTrace{Code: "0x6000fd", Ops: []types.VMTraceOp{{Pc: 0}, {Pc: 2}}}},
+				{Pc: 3},
+			}},
+		},
+		execution: types.ExecutionResult{
+			Failed:      true,
+			ReturnValue: output,
+			StructLogs: []types.StructLog{
+				{Pc: 0, Op: "PUSH1", Depth: 1, Gas: 100, GasCost: 3},
+				{Pc: 5, Op: "STATICCALL", Depth: 1, Gas: 97, GasCost: 40, Stack: []string{"0x0", "0xaa", "0x64"}},
+				{Pc: 0, Op: "PUSH1", Depth: 2, Gas: 50, GasCost: 3},
+				{Pc: 2, Op: "REVERT", Depth: 2, Gas: 47, GasCost: 0},
+				{Pc: 6, Op: "ISZERO", Depth: 1, Gas: 50, GasCost: 3},
+				{Pc: 9, Op: "REVERT", Depth: 1, Gas: 47, GasCost: 0},
+			},
+		},
+	}
+	mc, err := New(eth)
+	require.NoError(t, err)
+	call := NewViewCall("price", target, "latestAnswer()(int256)", nil)
+
+	failure, err := mc.TraceFailure(call, ethrpc.LatestBlock, ParityTracer, From("0x0000000000000000000000000000000000001234"))
+	require.NoError(t, err)
+	assert.Equal(t, "0x0000000000000000000000000000000000001234", eth.msg.From)
+	assert.Equal(t, target, eth.msg.To)
+	assert.Equal(t, "price", failure.ID)
+	assert.Equal(t, []string{target, oracle}, failure.Path)
+	assert.Equal(t, "REVERT", failure.Op)
+	assert.Equal(t, uint64(2), failure.PC)
+	assert.Equal(t, "Reverted", failure.Error)
+	assert.Equal(t, "stale price", failure.Reason)
+	assert.Len(t, failure.Trace, 2)
+
+	failure, err = mc.TraceFailure(call, ethrpc.LatestBlock, GethTracer)
+	require.NoError(t, err)
+	assert.True(t, eth.config.DisableStorage)
+	assert.Equal(t, []string{target, "0x00000000000000000000000000000000000000AA"}, failure.Path)
+	assert.Equal(t, "REVERT", failure.Op)
+	assert.Equal(t, uint64(2), failure.PC)
+	assert.Equal(t, "execution reverted", failure.Error)
+	assert.Equal(t, "stale price", failure.Reason)
+	require.Len(t, failure.Trace, 2)
+	assert.Equal(t, 1, failure.Trace[0].Subtraces)
+	assert.Equal(t, []int{0}, failure.Trace[1].TraceAddress)
+	assert.Equal(t, "staticcall", *failure.Trace[1].Action.CallType)
+	require.Len(t, failure.VMTrace.Ops, 4)
+	assert.Len(t, failure.VMTrace.Ops[1].Sub.Ops, 2)
+	assert.Equal(t, 57, failure.VMTrace.Ops[1].Ex.Used)
+
+	eth.execution = types.ExecutionResult{ReturnValue: "0x01"}
+	_, err = mc.TraceFailure(call, ethrpc.LatestBlock, GethTracer)
+	assert.ErrorIs(t, err, ErrCallSucceeded)
+}
@@ -0,0 +1,160 @@
+package multicall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/howjmay/multicall/utils"
+)
+
+// Tracer selects the tracing API TraceGas uses
+type Tracer int
+
+const (
+	// ParityTracer traces with trace_call, served by Erigon, Nethermind and
+	// OpenEthereum. trace_call takes no overrides, so it cannot be combined
+	// with CheckCode or the override CallOptions.
+	ParityTracer Tracer = iota
+	// GethTracer traces with debug_traceCall and geth's callTracer
+	GethTracer
+)
+
+// GasUsage is the gas a single call of a batch used
+type GasUsage struct {
+	ID     string
+	Target string
+	// GasUsed is the gas used by the call itself, excluding the overhead of
+	// the aggregate contract. trace_call reports no gas for failed calls, so
+	// it is 0 for them with ParityTracer.
+	GasUsed uint64
+	Failed  bool
+	Error   string
+}
+
+// GasReport is the gas used by the aggregate call of a batch and by each of
+// its calls, in batch order
+type GasReport struct {
+	// GasUsed is the gas used by the whole aggregate call. With GethTracer
+	// it includes the intrinsic gas of the transaction, 21000 plus the cost
+	// of the calldata and access list, as geth's callTracer reports it;
+	// trace_call leaves it out, so with ParityTracer it does not.
+	GasUsed uint64
+	Calls   []GasUsage
+}
+
+// TraceGas runs the aggregate call of calls through tracer and attributes
+// the gas it used to each call, e.g. to find the calls that need a higher
+// Config.Gas or a batch of their own
+func (mc multicall) TraceGas(calls ViewCalls, block ethrpc.BlockRef, tracer Tracer, opts ...CallOption) (*GasReport, error) {
+	request, err := mc.prepare(calls, opts)
+	if err != nil {
+		return nil, err
+	}
+	config, err := mc.callConfig(request, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []types.CallFrame
+	var total string
+	switch tracer {
+	case ParityTracer:
+		if config.state != nil || config.blockOverrides != nil {
+			return nil, errors.New("trace_call does not support state or block overrides")
+		}
+		replay, err := mc.eth.TraceCall(config.msg, block, "trace")
+		if err != nil {
+			return nil, err
+		}
+		total, frames, err = parityFrames(replay.Trace)
+		if err != nil {
+			return nil, err
+		}
+	case GethTracer:
+		frame, err := mc.eth.TraceCallFrames(config.msg, block, ethrpc.TraceConfig{
+			StateOverrides: config.state,
+			BlockOverrides: config.blockOverrides,
+		})
+		if err != nil {
+			return nil, err
+		}
+		total, frames = frame.GasUsed, frame.Calls
+	default:
+		return nil, fmt.Errorf("unknown tracer %d", tracer)
+	}
+
+	report := &GasReport{Calls: make([]GasUsage, len(calls))}
+	if report.GasUsed, err = parseGas(total); err != nil {
+		return nil, err
+	}
+	// the aggregate contract calls its targets in order, and the code size
+	// checks CheckCode appends come last
+	if len(frames) < len(calls) {
+		return nil, fmt.Errorf("trace holds %d calls for a batch of %d", len(frames), len(calls))
+	}
+	for i, call := range calls {
+		frame := frames[i]
+		if !strings.EqualFold(frame.To, common.HexToAddress(call.target).Hex()) {
+			return nil, fmt.Errorf("call %s: trace calls %s instead of %s", call.id, frame.To, call.target)
+		}
+		usage := GasUsage{
+			ID:     call.id,
+			Target: call.target,
+			Failed: frame.Error != "",
+			Error:  frame.Error,
+		}
+		if frame.GasUsed != "" {
+			if usage.GasUsed, err = parseGas(frame.GasUsed); err != nil {
+				return nil, fmt.Errorf("call %s: %w", call.id, err)
+			}
+		}
+		report.Calls[i] = usage
+	}
+	return report, nil
+}
+
+// parityFrames returns the gas used by the root of traces and its direct
+// subcalls as call frames
+func parityFrames(traces []types.Trace) (string, []types.CallFrame, error) {
+	var total string
+	var frames []types.CallFrame
+	for _, trace := range traces {
+		switch len(trace.TraceAddress) {
+		case 0:
+			if trace.Result != nil && trace.Result.GasUsed != nil {
+				total = *trace.Result.GasUsed
+			}
+		case 1:
+			var frame types.CallFrame
+			if trace.Action.To != nil {
+				frame.To = *trace.Action.To
+			}
+			if trace.Result != nil && trace.Result.GasUsed != nil {
+				frame.GasUsed = *trace.Result.GasUsed
+			}
+			if trace.Error != nil {
+				frame.Error = *trace.Error
+			}
+			frames = append(frames, frame)
+		}
+	}
+	if total == "" {
+		return "", nil, errors.New("trace_call returned no root trace")
+	}
+	return total, frames, nil
+}
+
+func parseGas(hexString string) (uint64, error) {
+	gas, err := utils.HexToBigInt(hexString)
+	if err != nil {
+		return 0, err
+	}
+	if !gas.IsUint64() {
+		return 0, fmt.Errorf("gas %s overflows uint64", hexString)
+	}
+	return gas.Uint64(), nil
+}
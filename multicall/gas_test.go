@@ -0,0 +1,84 @@
+package multicall
+
+import (
+	"testing"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tracingETH answers trace_call and debug_traceCall with fixed traces
+type tracingETH struct {
+	*fakeETH
+	replay    types.TransactionReplay
+	frame     types.CallFrame
+	execution types.ExecutionResult
+	msg       ethrpc.CallMsg
+	config    ethrpc.TraceConfig
+}
+
+func (t *tracingETH) TraceCall(msg ethrpc.CallMsg, block ethrpc.BlockRef, traceTypes ...string) (types.TransactionReplay, error) {
+	t.msg = msg
+	return t.replay, nil
+}
+
+func (t *tracingETH) TraceCallFrames(msg ethrpc.CallMsg, block ethrpc.BlockRef, config ethrpc.TraceConfig) (types.CallFrame, error) {
+	t.config = config
+	return t.frame, nil
+}
+
+func (t *tracingETH) DebugTraceCall(result interface{}, msg ethrpc.CallMsg, block ethrpc.BlockRef, config ethrpc.TraceConfig) error {
+	t.msg, t.config = msg, config
+	*result.(*types.ExecutionResult) = t.execution
+	return nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestTraceGas(t *testing.T) {
+	target := "0x0000000000000000000000000000000000000001"
+	eth := &tracingETH{
+		fakeETH: &fakeETH{},
+		replay: types.TransactionReplay{Trace: []types.Trace{
+			{Result: &types.TraceResult{GasUsed: stringPtr("0x7530")}},
+			{TraceAddress: []int{0}, Action: types.TraceAction{To: stringPtr(target)}, Result: &types.TraceResult{GasUsed: stringPtr("0x3e8")}},
+			{TraceAddress: []int{0, 0}, Action: types.TraceAction{To: stringPtr(target)}, Result: &types.TraceResult{GasUsed: stringPtr("0x64")}},
+			{TraceAddress: []int{1}, Action: types.TraceAction{To: stringPtr(target)}, Error: stringPtr("Reverted")},
+		}},
+		frame: types.CallFrame{GasUsed: "0x7530", Calls: []types.CallFrame{
+			{To: target, GasUsed: "0x3e8"},
+			{To: target, GasUsed: "0x1f4", Error: "execution reverted"},
+		}},
+	}
+	mc, err := New(eth)
+	require.NoError(t, err)
+	calls := numberedCalls(2)
+
+	report, err := mc.TraceGas(calls, ethrpc.LatestBlock, ParityTracer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30000), report.GasUsed)
+	assert.Equal(t, []GasUsage{
+		{ID: "0", Target: target, GasUsed: 1000},
+		{ID: "1", Target: target, Failed: true, Error: "Reverted"},
+	}, report.Calls)
+
+	report, err = mc.TraceGas(calls, ethrpc.LatestBlock, GethTracer, OverrideBlock(ethrpc.BlockOverrides{Time: "0x64"}))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30000), report.GasUsed)
+	assert.Equal(t, []GasUsage{
+		{ID: "0", Target: target, GasUsed: 1000},
+		{ID: "1", Target: target, GasUsed: 500, Failed: true, Error: "execution reverted"},
+	}, report.Calls)
+	assert.Equal(t, "0x64", eth.config.BlockOverrides.Time)
+
+	_, err = mc.TraceGas(calls, ethrpc.LatestBlock, ParityTracer, OverrideBlock(ethrpc.BlockOverrides{Time: "0x64"}))
+	assert.Error(t, err)
+
+	calls[1] = NewViewCall("1", "0x0000000000000000000000000000000000000002", "get(uint256)(uint256)", []interface{}{1})
+	_, err = mc.TraceGas(calls, ethrpc.LatestBlock, GethTracer)
+	assert.Error(t, err)
+}
@@ -0,0 +1,26 @@
+package multicall
+
+import (
+	"math/big"
+	"reflect"
+)
+
+// asInteger returns value as a *big.Int if it is a decoded integer, either
+// a big integer or one of the sized integer types the ABI decodes small
+// integers to
+func asInteger(value interface{}) (*big.Int, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if n, err := AsBigInt(value); err == nil {
+		return n, true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	return nil, false
+}
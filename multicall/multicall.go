@@ -2,27 +2,46 @@ package multicall
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/howjmay/multicall/ethrpc"
 	"github.com/howjmay/multicall/ethrpc/provider/httprpc"
 )
 
+// Multicall batches view calls into aggregate calls. Call returns the full
+// Result together with a *DecodeErrors when some calls could not be
+// decoded, unless StrictDecoding is set.
 type Multicall interface {
-	CallRaw(calls ViewCalls, block string) (*Result, error)
-	Call(calls ViewCalls, block string) (*Result, error)
+	CallRaw(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Result, error)
+	Call(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Result, error)
+	CallStream(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) *Stream
+	CallAtTime(calls ViewCalls, t time.Time, opts ...CallOption) (*Result, error)
+	Explain(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Explanation, error)
+	TraceGas(calls ViewCalls, block ethrpc.BlockRef, tracer Tracer, opts ...CallOption) (*GasReport, error)
+	TraceFailure(call ViewCall, block ethrpc.BlockRef, tracer Tracer, opts ...CallOption) (*Failure, error)
+	Limits() (Limits, bool)
 	Contract() string
 }
 
 type multicall struct {
-	eth    ethrpc.ETHInterface
-	config *Config
+	eth      ethrpc.ETHInterface
+	config   *Config
+	resolver *BlockResolver
 }
 
 func New(eth ethrpc.ETHInterface, opts ...Option) (Multicall, error) {
 	config := &Config{
 		MulticallAddress: MainnetAddress,
 		Gas:              "0x400000000",
+		ChunkSize:        DefaultChunkSize,
+		Aggregator:       StrictAggregator(false),
+		BlockTime:        DefaultBlockTime,
 	}
 
 	for _, opt := range opts {
@@ -30,52 +49,320 @@ func New(eth ethrpc.ETHInterface, opts ...Option) (Multicall, error) {
 	}
 
 	return &multicall{
-		eth:    eth,
-		config: config,
+		eth:      eth,
+		config:   config,
+		resolver: NewBlockResolver(eth, config.BlockTime),
 	}, nil
 }
 
 type CallResult struct {
 	Success bool
+	Status  CallStatus
 	Raw     []byte
 	Decoded []interface{}
+	// Named holds the named return values, e.g. reserve0 for
+	// "getReserves()(uint112 reserve0, uint112 reserve1, uint32 ts)"
+	Named map[string]interface{}
+	// DecodeError is set when the call succeeded but its return data does
+	// not match the declared return types. It is marshalled to JSON as its
+	// message.
+	DecodeError error `json:",omitempty"`
+}
+
+// MarshalJSON writes DecodeError as its message, which the error itself
+// does not marshal to
+func (c CallResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.jsonFields())
+}
+
+// callResultJSON is a CallResult as it is marshalled to JSON, its
+// DecodeError shadowing the one of callResult
+type callResultJSON struct {
+	callResult
+	DecodeError string `json:",omitempty"`
+}
+
+// callResult has the fields of CallResult without its MarshalJSON
+type callResult CallResult
+
+func (c CallResult) jsonFields() callResultJSON {
+	out := callResultJSON{callResult: callResult(c)}
+	if c.DecodeError != nil {
+		out.DecodeError = c.DecodeError.Error()
+	}
+	return out
 }
 
 type Result struct {
 	BlockNumber uint64
-	Calls       map[string]CallResult
+	// BlockHash is set when the calls ran against a block hash or when
+	// ResolveBlockHash is enabled
+	BlockHash string
+	// BlockTimestamp is the timestamp of the block, set by CallAtTime
+	BlockTimestamp uint64 `json:",omitempty"`
+	// EVMBlockNumber is block.number as the calls saw it, set by
+	// ChainSemantics. It differs from BlockNumber on chains like Arbitrum.
+	EVMBlockNumber uint64 `json:",omitempty"`
+	// L1 is the L1 state the block was built on, set by ChainSemantics of
+	// L2s
+	L1    *L1Info `json:",omitempty"`
+	Calls map[string]CallResult
+	// Computed holds the fields added with Compute
+	Computed map[string]ComputedResult `json:",omitempty"`
 }
 
-const AggregateMethod = "0x17352e13"
+// Values returns the decoded return values of the call with the given id,
+// failing if the call is missing, did not succeed or could not be decoded
+func (r *Result) Values(id string) ([]interface{}, error) {
+	callResult, ok := r.Calls[id]
+	if !ok {
+		return nil, fmt.Errorf("no result for call %s", id)
+	}
+	return callResult.values(id)
+}
 
-func (mc multicall) CallRaw(calls ViewCalls, block string) (*Result, error) {
-	resultRaw, err := mc.sendRequest(calls, block)
+func (callResult CallResult) values(id string) ([]interface{}, error) {
+	if !callResult.Success {
+		return nil, fmt.Errorf("call %s failed", id)
+	}
+	if callResult.DecodeError != nil {
+		return nil, fmt.Errorf("call %s: %w", id, callResult.DecodeError)
+	}
+	return callResult.Decoded, nil
+}
+
+// DecodeErrors lists the calls of a batch whose return data could not be
+// decoded
+type DecodeErrors struct {
+	Errors map[string]error
+}
+
+func (e *DecodeErrors) Error() string {
+	ids := make([]string, 0, len(e.Errors))
+	for id := range e.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	messages := make([]string, len(ids))
+	for i, id := range ids {
+		messages[i] = fmt.Sprintf("%s: %s", id, e.Errors[id])
+	}
+	return fmt.Sprintf("failed to decode %d call(s): %s", len(ids), strings.Join(messages, "; "))
+}
+
+func (mc multicall) CallRaw(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Result, error) {
+	if err := rejectComputed(opts, "CallRaw"); err != nil {
+		return nil, err
+	}
+	decoded, err := mc.aggregate(mc.withChainCalls(calls), block, opts)
+	if err != nil {
+		return nil, err
+	}
+	decoded, chain, err := mc.splitChainResult(decoded)
 	if err != nil {
 		return nil, err
 	}
-	return calls.decodeRaw(resultRaw)
+	result, err := calls.decodeRaw(decoded)
+	if err != nil {
+		return nil, err
+	}
+	if err := mc.applyChain(result, chain); err != nil {
+		return nil, err
+	}
+	return result, mc.setBlockHash(result, block)
 }
 
-func (mc multicall) Call(calls ViewCalls, block string) (*Result, error) {
-	resultRaw, err := mc.sendRequest(calls, block)
+func (mc multicall) Call(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Result, error) {
+	fields, err := computedFields(opts, calls)
 	if err != nil {
 		return nil, err
 	}
-	return calls.decode(resultRaw)
+	decoded, err := mc.aggregate(mc.withChainCalls(calls), block, opts)
+	if err != nil {
+		return nil, err
+	}
+	decoded, chain, err := mc.splitChainResult(decoded)
+	if err != nil {
+		return nil, err
+	}
+	result, decodeErr := calls.decode(decoded)
+	if decodeErr != nil && mc.config.StrictDecoding {
+		return nil, decodeErr
+	}
+	if err := mc.applyChain(result, chain); err != nil {
+		return nil, err
+	}
+	if err := mc.setBlockHash(result, block); err != nil {
+		return nil, err
+	}
+	result.compute(fields)
+	return result, decodeErr
 }
 
-func (mc multicall) sendRequest(calls ViewCalls, block string) (string, error) {
-	payloadArgs, err := calls.callData()
+// setBlockHash fills in the hash of the block the calls ran at
+func (mc multicall) setBlockHash(result *Result, block ethrpc.BlockRef) error {
+	if hash, ok := block.Hash(); ok {
+		result.BlockHash = hash
+		return nil
+	}
+	// a BlockNumber of 0 is unknown, the aggregator did not return it
+	if tag, _ := block.Tag(); result.BlockHash != "" || !mc.config.ResolveBlockHash || tag == ethrpc.BlockTag_Pending || result.BlockNumber == 0 {
+		return nil
+	}
+	header, err := mc.eth.GetBlockHeader(ethrpc.BlockNumberRef(result.BlockNumber))
 	if err != nil {
-		return "", err
+		return err
+	}
+	result.BlockHash = header.Hash
+	return nil
+}
+
+// aggregate sends calls in a single aggregate call, or in chunks when the
+// Multicall is adaptive, and decodes the aggregator's return value
+func (mc multicall) aggregate(calls ViewCalls, block ethrpc.BlockRef, opts []CallOption) (*AggregateResult, error) {
+	if mc.config.Limits != nil {
+		return mc.aggregateAdaptive(calls, block, opts)
+	}
+	return mc.aggregateOnce(calls, block, opts)
+}
+
+// aggregateOnce sends calls in a single aggregate call
+func (mc multicall) aggregateOnce(calls ViewCalls, block ethrpc.BlockRef, opts []CallOption) (*AggregateResult, error) {
+	request, err := mc.prepare(calls, opts)
+	if err != nil {
+		return nil, err
+	}
+	params, err := mc.callParams(request, block, opts)
+	if err != nil {
+		return nil, err
 	}
-	payload := make(map[string]string)
-	payload["to"] = mc.config.MulticallAddress
-	payload["data"] = AggregateMethod + hex.EncodeToString(payloadArgs)
-	payload["gas"] = mc.config.Gas
 	var resultRaw string
-	err = mc.eth.SendRequest(&resultRaw, ethrpc.ETH_Call, payload, block)
-	return resultRaw, err
+	if err := mc.eth.SendRequest(&resultRaw, ethrpc.ETH_Call, params...); err != nil {
+		return nil, err
+	}
+	rawBytes, err := hex.DecodeString(strings.TrimPrefix(resultRaw, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := mc.config.Aggregator.DecodeResults(rawBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded.Returns) != len(request.calls) {
+		return nil, fmt.Errorf("aggregator returned %d results for %d calls", len(decoded.Returns), len(request.calls))
+	}
+	if request.blockNumber {
+		if err := readBlockNumber(decoded); err != nil {
+			return nil, err
+		}
+	}
+	if mc.config.CheckCode {
+		if err := markNoCode(decoded, request.calls[:len(calls)], request.targets); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// aggregateRequest holds everything sent in one aggregate call
+type aggregateRequest struct {
+	calls []AggregateCall
+	// targets are the call targets whose code size is checked
+	targets   [][20]byte
+	overrides ethrpc.StateOverride
+	// blockNumber is set when a getBlockNumber() call comes last
+	blockNumber bool
+	// value is the sum of the values sent with the calls, nil unless the
+	// Value option is set
+	value *big.Int
+}
+
+func (mc multicall) prepare(calls ViewCalls, opts []CallOption) (*aggregateRequest, error) {
+	aggregateCalls, err := calls.aggregateCalls()
+	if err != nil {
+		return nil, err
+	}
+	request := &aggregateRequest{calls: aggregateCalls}
+	if err := mc.sendValue(request, calls, opts); err != nil {
+		return nil, err
+	}
+	if mc.config.CheckCode {
+		request.targets = uniqueTargets(aggregateCalls)
+		request.calls = append(request.calls, codeSizeCalls(request.targets)...)
+		request.overrides = codeSizeOverride()
+	}
+	if _, ok := mc.config.Aggregator.(blockNumberReader); ok {
+		call, err := getBlockNumberCall(mc.config.MulticallAddress)
+		if err != nil {
+			return nil, err
+		}
+		request.calls = append(request.calls, call)
+		request.blockNumber = true
+	}
+	return request, nil
+}
+
+// sendValue sets the value opts send on every call but those of
+// ChainSemantics, and their sum as the value of request
+func (mc multicall) sendValue(request *aggregateRequest, calls ViewCalls, opts []CallOption) error {
+	config := &callConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.value == nil {
+		return nil
+	}
+	if _, ok := mc.config.Aggregator.(valueSender); !ok {
+		return errors.New("sending value needs an aggregator that sends it with every call, such as Aggregate3ValueAggregator")
+	}
+	request.value = new(big.Int)
+	for i, call := range calls {
+		if strings.HasPrefix(call.id, chainCallPrefix) {
+			continue
+		}
+		request.calls[i].Value = config.value
+		request.value.Add(request.value, config.value)
+	}
+	return nil
+}
+
+// callParams builds the eth_call parameters of request
+func (mc multicall) callParams(request *aggregateRequest, block ethrpc.BlockRef, opts []CallOption) ([]interface{}, error) {
+	config, err := mc.callConfig(request, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ethrpc.CallParams(config.msg, block, config.state, config.blockOverrides), nil
+}
+
+// callConfig builds the eth_call of request, merging its overrides into the
+// state override set of opts
+func (mc multicall) callConfig(request *aggregateRequest, opts []CallOption) (*callConfig, error) {
+	callData, err := mc.config.Aggregator.EncodeCalls(request.calls)
+	if err != nil {
+		return nil, err
+	}
+	config := &callConfig{
+		msg: ethrpc.CallMsg{
+			To:   mc.config.MulticallAddress,
+			Data: "0x" + hex.EncodeToString(callData),
+			Gas:  mc.config.Gas,
+		},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	config.msg.Value = quantity(request.value)
+	for address, account := range request.overrides {
+		if _, ok := config.state[address]; ok {
+			return nil, fmt.Errorf("state override of %s conflicts with the code size check", address)
+		}
+		if config.state == nil {
+			config.state = make(ethrpc.StateOverride, len(request.overrides))
+		}
+		config.state[address] = account
+	}
+	return config, nil
 }
 
 func (mc multicall) Contract() string {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/howjmay/multicall/ethrpc"
 	"github.com/howjmay/multicall/multicall"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +21,7 @@ func TestExampleViewCall(t *testing.T) {
 	)
 	vcs := multicall.ViewCalls{vc}
 	mc, _ := multicall.New(eth)
-	block := "latest"
+	block := ethrpc.LatestBlock
 	res, err := mc.Call(vcs, block)
 	require.NoError(t, err)
 	require.True(t, res.Calls["SHIB-symbol"].Success)
@@ -0,0 +1,203 @@
+package multicall
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+const (
+	// DefaultChainTimeout is the default time a single chain gets to answer
+	DefaultChainTimeout = 10 * time.Second
+)
+
+// ErrChainTimeout is reported for chains that did not answer in time
+var ErrChainTimeout = errors.New("chain timed out")
+
+// ChainViewCall is a ViewCall tagged with the chain it runs on
+type ChainViewCall struct {
+	ChainID uint64
+	ViewCall
+}
+
+func NewChainViewCall(chainID uint64, call ViewCall) ChainViewCall {
+	return ChainViewCall{
+		ChainID:  chainID,
+		ViewCall: call,
+	}
+}
+
+// MultiChainError lists the chains that failed in a MultiChain call
+type MultiChainError struct {
+	Errors map[uint64]error
+}
+
+func (e *MultiChainError) Error() string {
+	chainIDs := make([]uint64, 0, len(e.Errors))
+	for chainID := range e.Errors {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+	messages := make([]string, len(chainIDs))
+	for i, chainID := range chainIDs {
+		messages[i] = fmt.Sprintf("chain %d: %s", chainID, e.Errors[chainID])
+	}
+	return fmt.Sprintf("%d chain(s) failed: %s", len(chainIDs), strings.Join(messages, "; "))
+}
+
+// MultiChainResult holds the Result of every chain that answered and the
+// error of every chain that did not. A chain whose calls could not all be
+// decoded has both a Result and a *DecodeErrors.
+type MultiChainResult struct {
+	Results map[uint64]*Result
+	Errors  map[uint64]error
+}
+
+type MultiChainOption func(*MultiChain)
+
+// ChainTimeout sets the timeout for every chain without its own timeout
+func ChainTimeout(timeout time.Duration) MultiChainOption {
+	return func(m *MultiChain) {
+		m.timeout = timeout
+	}
+}
+
+// ChainTimeoutFor sets the timeout for a single chain
+func ChainTimeoutFor(chainID uint64, timeout time.Duration) MultiChainOption {
+	return func(m *MultiChain) {
+		m.timeouts[chainID] = timeout
+	}
+}
+
+// MultiChain runs batches spanning several chains, using one Multicall
+// per chain ID
+type MultiChain struct {
+	mu       sync.RWMutex
+	chains   map[uint64]Multicall
+	timeout  time.Duration
+	timeouts map[uint64]time.Duration
+}
+
+func NewMultiChain(opts ...MultiChainOption) *MultiChain {
+	m := &MultiChain{
+		chains:   make(map[uint64]Multicall),
+		timeout:  DefaultChainTimeout,
+		timeouts: make(map[uint64]time.Duration),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// AddChain registers the Multicall used for chainID, replacing any
+// previous one
+func (m *MultiChain) AddChain(chainID uint64, mc Multicall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chains[chainID] = mc
+}
+
+// AddChainURL connects to the http endpoint url and registers a Multicall
+// for chainID configured with opts
+func (m *MultiChain) AddChainURL(chainID uint64, url string, opts ...Option) error {
+	eth, err := GetETH(url)
+	if err != nil {
+		return err
+	}
+	mc, err := New(eth, opts...)
+	if err != nil {
+		return err
+	}
+	m.AddChain(chainID, mc)
+	return nil
+}
+
+// Chains returns the registered chain IDs in ascending order
+func (m *MultiChain) Chains() []uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chainIDs := make([]uint64, 0, len(m.chains))
+	for chainID := range m.chains {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	return chainIDs
+}
+
+// Call groups calls by chain and runs every chain in parallel, each under
+// its own timeout. The returned result always holds the chains that
+// succeeded; if any chain failed the error is a *MultiChainError. A chain
+// that times out keeps its request running in the background until the
+// underlying provider gives up.
+func (m *MultiChain) Call(calls []ChainViewCall, block ethrpc.BlockRef) (*MultiChainResult, error) {
+	byChain := make(map[uint64]ViewCalls)
+	for _, call := range calls {
+		byChain[call.ChainID] = append(byChain[call.ChainID], call.ViewCall)
+	}
+
+	result := &MultiChainResult{
+		Results: make(map[uint64]*Result, len(byChain)),
+		Errors:  make(map[uint64]error),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for chainID, chainCalls := range byChain {
+		wg.Add(1)
+		go func(chainID uint64, chainCalls ViewCalls) {
+			defer wg.Done()
+			res, err := m.callChain(chainID, chainCalls, block)
+			mu.Lock()
+			defer mu.Unlock()
+			if res != nil {
+				result.Results[chainID] = res
+			}
+			if err != nil {
+				result.Errors[chainID] = err
+			}
+		}(chainID, chainCalls)
+	}
+	wg.Wait()
+
+	if len(result.Errors) > 0 {
+		return result, &MultiChainError{Errors: result.Errors}
+	}
+	return result, nil
+}
+
+type chainCallResult struct {
+	result *Result
+	err    error
+}
+
+func (m *MultiChain) callChain(chainID uint64, calls ViewCalls, block ethrpc.BlockRef) (*Result, error) {
+	m.mu.RLock()
+	mc, ok := m.chains[chainID]
+	timeout, hasTimeout := m.timeouts[chainID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no multicall registered for chain %d", chainID)
+	}
+	if !hasTimeout {
+		timeout = m.timeout
+	}
+
+	done := make(chan chainCallResult, 1)
+	go func() {
+		res, err := mc.Call(calls, block)
+		done <- chainCallResult{res, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.result, res.err
+	case <-time.After(timeout):
+		return nil, ErrChainTimeout
+	}
+}
@@ -0,0 +1,54 @@
+package multicall
+
+import (
+	"testing"
+	"time"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowMulticall struct {
+	Multicall
+	delay time.Duration
+}
+
+func (s slowMulticall) Call(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) (*Result, error) {
+	time.Sleep(s.delay)
+	return &Result{}, nil
+}
+
+func TestMultiChainCall(t *testing.T) {
+	mainnet, err := New(&fakeETH{blockNumber: 1, respond: echoArgument})
+	require.NoError(t, err)
+	arbitrum, err := New(&fakeETH{blockNumber: 2, respond: echoArgument})
+	require.NoError(t, err)
+
+	m := NewMultiChain(ChainTimeoutFor(10, 10*time.Millisecond))
+	m.AddChain(1, mainnet)
+	m.AddChain(42161, arbitrum)
+	m.AddChain(10, slowMulticall{delay: time.Second})
+	assert.Equal(t, []uint64{1, 10, 42161}, m.Chains())
+
+	calls := numberedCalls(3)
+	res, err := m.Call([]ChainViewCall{
+		NewChainViewCall(1, calls[0]),
+		NewChainViewCall(1, calls[1]),
+		NewChainViewCall(42161, calls[2]),
+		NewChainViewCall(10, calls[0]),
+		NewChainViewCall(137, calls[0]),
+	}, ethrpc.LatestBlock)
+
+	var multiChainErr *MultiChainError
+	require.ErrorAs(t, err, &multiChainErr)
+	assert.Len(t, multiChainErr.Errors, 2)
+	assert.ErrorIs(t, res.Errors[10], ErrChainTimeout)
+	assert.Error(t, res.Errors[137])
+
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, uint64(1), res.Results[1].BlockNumber)
+	assert.Len(t, res.Results[1].Calls, 2)
+	assert.Equal(t, uint64(2), res.Results[42161].BlockNumber)
+	assert.Len(t, res.Results[42161].Calls, 1)
+}
@@ -1,12 +1,42 @@
 package multicall
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Option func(*Config)
 
 type Config struct {
 	MulticallAddress string
-	Gas				 string
+	Gas              string
+	// ChunkSize is the maximum number of calls sent in one aggregate call by
+	// CallStream and by an adaptive Multicall
+	ChunkSize int
+	// ResolveBlockHash looks up the hash of the block the calls ran at,
+	// costing one extra request per call
+	ResolveBlockHash bool
+	// Aggregator encodes and decodes the aggregate call for the contract at
+	// MulticallAddress
+	Aggregator Aggregator
+	// CheckCode checks the code size of every call target in the same
+	// aggregate call and reports calls to addresses without code with
+	// StatusNoCode
+	CheckCode bool
+	// StrictDecoding makes Call fail as a whole when a single call cannot
+	// be decoded instead of reporting it in CallResult.DecodeError
+	StrictDecoding bool
+	// BlockTime is the average block time of the chain, used by CallAtTime
+	// to guess where to start searching for a block
+	BlockTime time.Duration
+	// Limits makes the Multicall adaptive: it splits Call into chunks and
+	// learns the chunk size and gas the endpoint accepts, keeping them in
+	// Limits under Endpoint
+	Limits   *LimitStore
+	Endpoint string
+	// Chain describes how the chain differs from Ethereum, nil for chains
+	// that do not
+	Chain ChainSemantics
 }
 
 const (
@@ -14,8 +44,17 @@ const (
 	MainnetAddress = "0x5eb3fa2dfecdde21c950813c665e9364fa609bd2"
 	// RopstenMulticall : Multicall contract address on Ropsten
 	RopstenAddress = "0xf3ad7e31b052ff96566eedd218a823430e74b406"
-)
+	// MakerMainnetAddress : MakerDAO's original Multicall contract address on mainnet, use with MakerAggregator
+	MakerMainnetAddress = "0xeefba1e63905ef1d7acba5a8513c70307c1ce441"
+	// Multicall2MainnetAddress : Multicall2 contract address on mainnet, use with TryAggregator, BlockAndAggregator or TryBlockAndAggregator
+	Multicall2MainnetAddress = "0x5ba1e12693dc8f9c48aad8770482f4739beed696"
+	// Multicall3Address : Multicall3 contract address, the same on most chains, use with Aggregate3Aggregator
+	Multicall3Address = "0xca11bde05977b3631167028862be2a173976ca11"
 
+	// DefaultChunkSize is the default number of calls per aggregate call
+	// when streaming results
+	DefaultChunkSize = 1000
+)
 
 func ContractAddress(address string) Option {
 	return func(c *Config) {
@@ -34,3 +73,61 @@ func SetGasHex(gas string) Option {
 		c.Gas = gas
 	}
 }
+
+func ChunkSize(size int) Option {
+	return func(c *Config) {
+		c.ChunkSize = size
+	}
+}
+
+func ResolveBlockHash() Option {
+	return func(c *Config) {
+		c.ResolveBlockHash = true
+	}
+}
+
+// WithAggregator selects the aggregate function of the contract at
+// MulticallAddress
+func WithAggregator(aggregator Aggregator) Option {
+	return func(c *Config) {
+		c.Aggregator = aggregator
+	}
+}
+
+// CheckCode enables code size checks for call targets. The check deploys a
+// helper contract through the eth_call state override set, which the node
+// has to support.
+func CheckCode() Option {
+	return func(c *Config) {
+		c.CheckCode = true
+	}
+}
+
+func StrictDecoding() Option {
+	return func(c *Config) {
+		c.StrictDecoding = true
+	}
+}
+
+// BlockTime sets the average block time of the chain
+func BlockTime(blockTime time.Duration) Option {
+	return func(c *Config) {
+		c.BlockTime = blockTime
+	}
+}
+
+// Adaptive learns the chunk size and gas endpoint accepts from the errors
+// it returns and keeps them in store, DefaultLimitStore when nil. Call and
+// CallStream then send chunks of the learned size, shrinking them when the
+// endpoint fails to answer and growing them again up to ChunkSize, and
+// lower the gas when the endpoint rejects it. Use the same endpoint name
+// for every Multicall of an endpoint.
+func Adaptive(store *LimitStore, endpoint string) Option {
+	return func(c *Config) {
+		if store == nil {
+			store = DefaultLimitStore
+		}
+		c.Limits = store
+		c.Endpoint = endpoint
+	}
+}
@@ -0,0 +1,174 @@
+package multicall
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+// DeriveFunc builds the calls of a pipeline step from the results of the
+// steps it runs after
+type DeriveFunc func(res *Result) (ViewCalls, error)
+
+type pipelineStep struct {
+	name   string
+	after  []string
+	derive DeriveFunc
+}
+
+// Pipeline runs calls that depend on the results of earlier calls, such as
+// factory.getPair(a,b) followed by pair.getReserves(). Steps are grouped
+// into as few sequential aggregate calls as their dependencies allow, and
+// every aggregate call after the first is pinned to the block the first
+// one ran at.
+type Pipeline struct {
+	mc    Multicall
+	steps []pipelineStep
+}
+
+func NewPipeline(mc Multicall) *Pipeline {
+	return &Pipeline{mc: mc}
+}
+
+// Add adds a step with fixed calls, sent in the first aggregate call
+func (p *Pipeline) Add(name string, calls ViewCalls) *Pipeline {
+	return p.Derive(name, nil, func(*Result) (ViewCalls, error) {
+		return calls, nil
+	})
+}
+
+// Derive adds a step whose calls are built by derive once every step named
+// in after has run. derive gets the combined Result of all steps run so
+// far.
+func (p *Pipeline) Derive(name string, after []string, derive DeriveFunc) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{name: name, after: after, derive: derive})
+	return p
+}
+
+// Run runs every step at block with opts and returns the combined Result,
+// with the fields added by Compute evaluated over the calls of all steps.
+// Like Multicall.Call it returns the Result together with a *DecodeErrors
+// when some calls could not be decoded.
+func (p *Pipeline) Run(block ethrpc.BlockRef, opts ...CallOption) (*Result, error) {
+	levels, err := p.levels()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := computedFields(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Calls: make(map[string]CallResult)}
+	decodeErrs := make(map[string]error)
+	pinned := false
+	for _, steps := range levels {
+		var batch ViewCalls
+		for _, step := range steps {
+			calls, err := step.derive(result)
+			if err != nil {
+				return nil, fmt.Errorf("pipeline step %s: %w", step.name, err)
+			}
+			batch = append(batch, calls...)
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		seen := make(map[string]bool, len(batch))
+		for _, call := range batch {
+			if _, ok := result.Calls[call.id]; ok || seen[call.id] {
+				return nil, fmt.Errorf("duplicate call id %s in pipeline", call.id)
+			}
+			seen[call.id] = true
+		}
+
+		// the computed fields are evaluated once every step ran
+		res, err := p.mc.Call(batch, block, withoutComputed(opts)...)
+		// with StrictDecoding decode errors come without a Result and
+		// fail the run
+		var decodeErr *DecodeErrors
+		if err != nil && (res == nil || !errors.As(err, &decodeErr)) {
+			return nil, err
+		}
+		if decodeErr != nil {
+			for id, err := range decodeErr.Errors {
+				decodeErrs[id] = err
+			}
+		}
+		if !pinned {
+			result.BlockNumber = res.BlockNumber
+			result.BlockHash = res.BlockHash
+			block = block.Pin(res.BlockNumber)
+			pinned = true
+		}
+		for id, call := range res.Calls {
+			result.Calls[id] = call
+		}
+	}
+
+	// computed fields may refer to calls of every step
+	result.compute(fields)
+	if len(decodeErrs) > 0 {
+		return result, &DecodeErrors{Errors: decodeErrs}
+	}
+	return result, nil
+}
+
+// levels groups the steps by the number of aggregate calls that have to run
+// before them, keeping the order they were added in within a level
+func (p *Pipeline) levels() ([][]pipelineStep, error) {
+	byName := make(map[string]int, len(p.steps))
+	for i, step := range p.steps {
+		if _, ok := byName[step.name]; ok {
+			return nil, fmt.Errorf("duplicate pipeline step %s", step.name)
+		}
+		byName[step.name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(p.steps))
+	depth := make([]int, len(p.steps))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("pipeline step %s depends on itself", p.steps[i].name)
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		for _, name := range p.steps[i].after {
+			j, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("pipeline step %s runs after unknown step %s", p.steps[i].name, name)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+			if depth[j]+1 > depth[i] {
+				depth[i] = depth[j] + 1
+			}
+		}
+		state[i] = done
+		return nil
+	}
+
+	var levels [][]pipelineStep
+	for i := range p.steps {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	for i, step := range p.steps {
+		for len(levels) <= depth[i] {
+			levels = append(levels, nil)
+		}
+		levels[depth[i]] = append(levels[depth[i]], step)
+	}
+	return levels, nil
+}
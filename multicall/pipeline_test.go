@@ -0,0 +1,104 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const factoryAddress = "0x0000000000000000000000000000000000000001"
+
+// respondFactory answers allPairsLength() with 3, allPairs(i) with pair
+// address 0x10+i and getReserves() on a pair with its index twice
+func respondFactory(target [20]byte, callData []byte) AggregateReturn {
+	word := func(n int64) []byte {
+		return common.LeftPadBytes(big.NewInt(n).Bytes(), 32)
+	}
+	switch {
+	case target[19] == 1 && len(callData) == 4:
+		return AggregateReturn{Success: true, Data: word(3)}
+	case target[19] == 1:
+		index := new(big.Int).SetBytes(callData[4:]).Int64()
+		return AggregateReturn{Success: true, Data: word(0x10 + index)}
+	}
+	index := int64(target[19] - 0x10)
+	return AggregateReturn{Success: true, Data: append(word(index), word(index)...)}
+}
+
+func TestPipeline(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: respondFactory}
+	mc, err := New(eth)
+	require.NoError(t, err)
+
+	res, err := NewPipeline(mc).
+		Derive("reserves", []string{"pairs"}, func(res *Result) (ViewCalls, error) {
+			var calls ViewCalls
+			for i := 0; ; i++ {
+				values, err := res.Values(fmt.Sprintf("pair%d", i))
+				if err != nil {
+					break
+				}
+				pair := values[0].(common.Address).Hex()
+				calls = append(calls, NewViewCall(fmt.Sprintf("reserves%d", i), pair, "getReserves()(uint112,uint112)", nil))
+			}
+			return calls, nil
+		}).
+		Add("length", ViewCalls{NewViewCall("length", factoryAddress, "allPairsLength()(uint256)", nil)}).
+		Derive("pairs", []string{"length"}, func(res *Result) (ViewCalls, error) {
+			values, err := res.Values("length")
+			if err != nil {
+				return nil, err
+			}
+			length, err := AsBigInt(values[0])
+			if err != nil {
+				return nil, err
+			}
+			calls := make(ViewCalls, length.Int64())
+			for i := range calls {
+				calls[i] = NewViewCall(fmt.Sprintf("pair%d", i), factoryAddress, "allPairs(uint256)(address)", []interface{}{i})
+			}
+			return calls, nil
+		}).
+		Add("other", ViewCalls{NewViewCall("other", factoryAddress, "allPairs(uint256)(address)", []interface{}{7})}).
+		Run(ethrpc.LatestBlock)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, eth.requests)
+	assert.Equal(t, ethrpc.BlockNumberRef(42), eth.params[1])
+	assert.Equal(t, uint64(42), res.BlockNumber)
+	assert.Len(t, res.Calls, 8)
+	values, err := res.Values("reserves2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), values[1].(*BigIntJSONString).ToBigInt().Int64())
+}
+
+func TestPipelineStrictDecoding(t *testing.T) {
+	bad := ViewCalls{NewViewCall("bad", "0x0000000000000000000000000000000000000001", "get(uint256)(string)", []interface{}{2})}
+	mc, err := New(&fakeETH{blockNumber: 42, respond: echoArgument}, StrictDecoding())
+	require.NoError(t, err)
+
+	res, err := NewPipeline(mc).Add("bad", bad).Run(ethrpc.LatestBlock)
+	var decodeErr *DecodeErrors
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Nil(t, res)
+}
+
+func TestPipelineInvalidSteps(t *testing.T) {
+	mc, err := New(&fakeETH{respond: echoArgument})
+	require.NoError(t, err)
+	noCalls := func(*Result) (ViewCalls, error) { return nil, nil }
+
+	_, err = NewPipeline(mc).Derive("a", []string{"b"}, noCalls).Derive("b", []string{"a"}, noCalls).Run(ethrpc.LatestBlock)
+	assert.Error(t, err)
+
+	_, err = NewPipeline(mc).Derive("a", []string{"missing"}, noCalls).Run(ethrpc.LatestBlock)
+	assert.Error(t, err)
+
+	_, err = NewPipeline(mc).Add("a", numberedCalls(2)).Add("b", numberedCalls(1)).Run(ethrpc.LatestBlock)
+	assert.Error(t, err)
+}
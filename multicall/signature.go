@@ -0,0 +1,407 @@
+package multicall
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// param is a parameter of a method signature. Tuples carry their
+// components and an array suffix such as "[]" or "[2]".
+type param struct {
+	name       string
+	typ        string
+	components []param
+}
+
+// signature is a parsed method string such as
+// "getReserves()(uint112 reserve0, uint112 reserve1, uint32 ts)" or an
+// ethers human-readable ABI fragment such as
+// "function balanceOf(address owner) view returns (uint256)"
+type signature struct {
+	name string
+	// selectorText is the canonical signature hashed to get the function
+	// selector
+	selectorText string
+	args         []param
+	returns      []param
+}
+
+// compiledMethod is a parsed method string together with everything
+// derived from it that encoding and decoding need
+type compiledMethod struct {
+	sig      *signature
+	selector []byte
+	argTypes []string
+	args     abi.Arguments
+	returns  abi.Arguments
+}
+
+// compiledMethods caches a compiledMethod per method string. Batches are
+// typically built from a small set of method strings, so the cache is not
+// bounded.
+var compiledMethods sync.Map
+
+func compileMethod(method string) (*compiledMethod, error) {
+	if compiled, ok := compiledMethods.Load(method); ok {
+		return compiled.(*compiledMethod), nil
+	}
+	sig, err := parseSignature(method)
+	if err != nil {
+		return nil, err
+	}
+	args, err := abiArguments(sig.args)
+	if err != nil {
+		return nil, err
+	}
+	returns, err := abiArguments(sig.returns)
+	if err != nil {
+		return nil, err
+	}
+	argTypes := make([]string, len(sig.args))
+	for i, arg := range sig.args {
+		argTypes[i] = arg.canonicalType()
+	}
+	compiled := &compiledMethod{
+		sig: sig,
+		// capped so appending to it never writes into the cached hash
+		selector: crypto.Keccak256([]byte(sig.selectorText))[:4:4],
+		argTypes: argTypes,
+		args:     args,
+		returns:  returns,
+	}
+	compiledMethods.Store(method, compiled)
+	return compiled, nil
+}
+
+func parseSignature(method string) (*signature, error) {
+	method = strings.TrimSpace(method)
+	if strings.HasPrefix(method, fragmentPrefix) {
+		return parseFragment(method)
+	}
+	open := strings.Index(method, "(")
+	if open <= 0 {
+		return nil, fmt.Errorf("invalid method %q: missing argument list", method)
+	}
+	argsEnd, err := matchingParen(method, open)
+	if err != nil {
+		return nil, fmt.Errorf("invalid method %q: %w", method, err)
+	}
+	sig := &signature{name: strings.TrimSpace(method[:open])}
+	if sig.args, err = parseParams(method[open+1 : argsEnd]); err != nil {
+		return nil, fmt.Errorf("invalid arguments in method %q: %w", method, err)
+	}
+	sig.selectorText = canonicalSignature(sig.name, sig.args)
+
+	rest := strings.TrimSpace(method[argsEnd+1:])
+	if rest == "" {
+		return sig, nil
+	}
+	if rest[0] != '(' {
+		return nil, fmt.Errorf("invalid method %q: unexpected %q after arguments", method, rest)
+	}
+	returnsEnd, err := matchingParen(rest, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid method %q: %w", method, err)
+	}
+	if strings.TrimSpace(rest[returnsEnd+1:]) != "" {
+		return nil, fmt.Errorf("invalid method %q: unexpected %q after return types", method, rest[returnsEnd+1:])
+	}
+	if sig.returns, err = parseParams(rest[1:returnsEnd]); err != nil {
+		return nil, fmt.Errorf("invalid return types in method %q: %w", method, err)
+	}
+	return sig, nil
+}
+
+const fragmentPrefix = "function "
+
+// fragmentModifiers may appear between the arguments and the returns
+// clause of a human-readable ABI fragment
+var fragmentModifiers = map[string]bool{
+	"view":       true,
+	"pure":       true,
+	"constant":   true,
+	"payable":    true,
+	"nonpayable": true,
+	"external":   true,
+	"public":     true,
+}
+
+// parseFragment parses an ethers human-readable ABI function fragment.
+// Like for method strings, names, modifiers and whitespace do not affect
+// the selector.
+func parseFragment(fragment string) (*signature, error) {
+	s := strings.TrimSpace(strings.TrimPrefix(fragment, fragmentPrefix))
+	open := strings.Index(s, "(")
+	if open <= 0 {
+		return nil, fmt.Errorf("invalid fragment %q: missing argument list", fragment)
+	}
+	argsEnd, err := matchingParen(s, open)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fragment %q: %w", fragment, err)
+	}
+	sig := &signature{name: strings.TrimSpace(s[:open]), returns: []param{}}
+	if sig.args, err = parseParams(s[open+1 : argsEnd]); err != nil {
+		return nil, fmt.Errorf("invalid arguments in fragment %q: %w", fragment, err)
+	}
+	sig.selectorText = canonicalSignature(sig.name, sig.args)
+
+	rest := s[argsEnd+1:]
+	modifiers := rest
+	if index := strings.Index(rest, "returns"); index >= 0 {
+		modifiers = rest[:index]
+		rest = strings.TrimSpace(rest[index+len("returns"):])
+		if rest == "" || rest[0] != '(' {
+			return nil, fmt.Errorf("invalid fragment %q: missing return types", fragment)
+		}
+		returnsEnd, err := matchingParen(rest, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fragment %q: %w", fragment, err)
+		}
+		if strings.TrimSpace(rest[returnsEnd+1:]) != "" {
+			return nil, fmt.Errorf("invalid fragment %q: unexpected %q after return types", fragment, rest[returnsEnd+1:])
+		}
+		if sig.returns, err = parseParams(rest[1:returnsEnd]); err != nil {
+			return nil, fmt.Errorf("invalid return types in fragment %q: %w", fragment, err)
+		}
+	}
+	for _, modifier := range strings.Fields(modifiers) {
+		if !fragmentModifiers[modifier] {
+			return nil, fmt.Errorf("invalid fragment %q: unknown modifier %q", fragment, modifier)
+		}
+	}
+	return sig, nil
+}
+
+// canonicalSignature returns the text hashed into the selector of the
+// function name taking args, e.g. "balanceOf(address)" for
+// "balanceOf(address owner)"
+func canonicalSignature(name string, args []param) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = arg.canonicalType()
+	}
+	return name + "(" + strings.Join(types, ",") + ")"
+}
+
+// paramModifiers are data locations and event keywords that do not change
+// the type of a parameter
+var paramModifiers = map[string]bool{
+	"memory":   true,
+	"calldata": true,
+	"storage":  true,
+	"indexed":  true,
+}
+
+// typeAliases maps shorthand types to their canonical form
+var typeAliases = map[string]string{
+	"uint": "uint256",
+	"int":  "int256",
+	"byte": "bytes1",
+}
+
+func canonicalBaseType(typ string) string {
+	base, suffix := typ, ""
+	if index := strings.Index(typ, "["); index >= 0 {
+		base, suffix = typ[:index], typ[index:]
+	}
+	if alias, ok := typeAliases[base]; ok {
+		return alias + suffix
+	}
+	return typ
+}
+
+// paramFields splits what follows a parameter type into an optional array
+// suffix and name, dropping modifiers
+func paramFields(s string) []string {
+	var fields []string
+	for _, field := range strings.Fields(s) {
+		if !paramModifiers[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open
+func matchingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced parentheses")
+}
+
+// splitParams splits a parameter list on its top level commas
+func splitParams(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, list[start:])
+}
+
+func parseParams(list string) ([]param, error) {
+	if strings.TrimSpace(list) == "" {
+		return []param{}, nil
+	}
+	parts := splitParams(list)
+	params := make([]param, len(parts))
+	for i, part := range parts {
+		p, err := parseParam(part)
+		if err != nil {
+			return nil, err
+		}
+		params[i] = p
+	}
+	return params, nil
+}
+
+func parseParam(s string) (param, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return param{}, fmt.Errorf("empty parameter")
+	}
+	if strings.HasPrefix(s, "tuple(") {
+		s = strings.TrimPrefix(s, "tuple")
+	}
+	if s[0] == '(' {
+		end, err := matchingParen(s, 0)
+		if err != nil {
+			return param{}, err
+		}
+		components, err := parseParams(s[1:end])
+		if err != nil {
+			return param{}, err
+		}
+		fields := paramFields(s[end+1:])
+		p := param{typ: "tuple", components: components}
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "[") {
+			p.typ += fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) > 1 {
+			return param{}, fmt.Errorf("invalid parameter %q", s)
+		}
+		if len(fields) == 1 {
+			p.name = fields[0]
+		}
+		return p, nil
+	}
+
+	fields := paramFields(s)
+	if len(fields) > 2 {
+		return param{}, fmt.Errorf("invalid parameter %q", s)
+	}
+	p := param{typ: canonicalBaseType(fields[0])}
+	if len(fields) == 2 {
+		p.name = fields[1]
+	}
+	return p, nil
+}
+
+// canonicalType returns the type as it appears in a canonical signature,
+// with tuples spelled out as their components
+func (p param) canonicalType() string {
+	if p.components == nil {
+		return p.typ
+	}
+	types := make([]string, len(p.components))
+	for i, component := range p.components {
+		types[i] = component.canonicalType()
+	}
+	return "(" + strings.Join(types, ",") + ")" + strings.TrimPrefix(p.typ, "tuple")
+}
+
+// key is the name a value is exposed under, its index if unnamed
+func (p param) key(index int) string {
+	if p.name != "" {
+		return p.name
+	}
+	return strconv.Itoa(index)
+}
+
+func (p param) marshaling(index int) abi.ArgumentMarshaling {
+	m := abi.ArgumentMarshaling{Name: p.name, Type: p.typ}
+	if strings.Trim(p.name, "_") == "" {
+		// go-ethereum builds a struct for tuples and needs a usable field
+		// name, the declared names are mapped back when decoding
+		m.Name = fmt.Sprintf("Field%d", index)
+	}
+	for i, component := range p.components {
+		m.Components = append(m.Components, component.marshaling(i))
+	}
+	return m
+}
+
+func (p param) abiType() (abi.Type, error) {
+	m := p.marshaling(0)
+	return abi.NewType(m.Type, "", m.Components)
+}
+
+func abiArguments(params []param) (abi.Arguments, error) {
+	args := make(abi.Arguments, len(params))
+	for i, p := range params {
+		typ, err := p.abiType()
+		if err != nil {
+			return nil, err
+		}
+		args[i] = abi.Argument{Name: fmt.Sprintf("ret%d", i), Type: typ}
+	}
+	return args, nil
+}
+
+// normalizeValue converts a value unpacked by go-ethereum into plain Go
+// values: tuples become maps keyed by component name (or index when
+// unnamed), arrays and slices become []interface{} and integers that do not
+// fit 64 bits become *BigIntJSONString, at any depth
+func normalizeValue(value interface{}, typ abi.Type, p param) interface{} {
+	switch typ.T {
+	case abi.TupleTy:
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		tuple := make(map[string]interface{}, len(typ.TupleElems))
+		for i, elemType := range typ.TupleElems {
+			tuple[p.components[i].key(i)] = normalizeValue(v.Field(i).Interface(), *elemType, p.components[i])
+		}
+		return tuple
+	case abi.SliceTy, abi.ArrayTy:
+		v := reflect.ValueOf(value)
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = normalizeValue(v.Index(i).Interface(), *typ.Elem, p)
+		}
+		return list
+	}
+	if bigint, ok := value.(*big.Int); ok {
+		return (*BigIntJSONString)(bigint)
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+package multicall
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/howjmay/multicall/ethrpc"
+)
+
+// StreamResult is the result of a single call emitted by CallStream
+type StreamResult struct {
+	ID          string
+	BlockNumber uint64
+	CallResult
+}
+
+// MarshalJSON writes the fields of CallResult next to ID and BlockNumber,
+// instead of only the CallResult its promoted MarshalJSON would write
+func (r StreamResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID          string
+		BlockNumber uint64
+		callResultJSON
+	}{r.ID, r.BlockNumber, r.CallResult.jsonFields()})
+}
+
+// Stream delivers the results of CallStream as each chunk completes.
+// Results is closed once every call has been emitted, the stream was
+// cancelled or a chunk failed, in which case the error is sent on Errors
+// first. Errors is closed after Results.
+type Stream struct {
+	Results <-chan StreamResult
+	Errors  <-chan error
+
+	done chan struct{}
+	once sync.Once
+}
+
+// Cancel stops the stream. Chunks that have not been requested yet are
+// never sent and Results is closed shortly after.
+func (s *Stream) Cancel() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// CallStream splits calls into chunks of Config.ChunkSize and sends one
+// aggregate call per chunk, emitting results in call order as soon as their
+// chunk is decoded. Calls that cannot be decoded are emitted with their
+// DecodeError set. Only one chunk is held in memory at a time, and every
+// chunk after the first is pinned to the block the first one ran at.
+// Computed fields would only see a single chunk, so Compute options fail
+// the stream.
+func (mc multicall) CallStream(calls ViewCalls, block ethrpc.BlockRef, opts ...CallOption) *Stream {
+	results := make(chan StreamResult, mc.chunkSize(len(calls)))
+	errs := make(chan error, 1)
+	stream := &Stream{
+		Results: results,
+		Errors:  errs,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(results)
+
+		if err := rejectComputed(opts, "CallStream"); err != nil {
+			errs <- err
+			return
+		}
+		size := mc.chunkSize(len(calls))
+		for start := 0; start < len(calls); start += size {
+			select {
+			case <-stream.done:
+				return
+			default:
+			}
+
+			end := start + size
+			if end > len(calls) {
+				end = len(calls)
+			}
+			chunk := calls[start:end]
+			res, err := mc.Call(chunk, block, opts...)
+			if res == nil {
+				errs <- err
+				return
+			}
+			if start == 0 {
+				block = block.Pin(res.BlockNumber)
+			}
+			for _, call := range chunk {
+				select {
+				case results <- StreamResult{ID: call.id, BlockNumber: res.BlockNumber, CallResult: res.Calls[call.id]}:
+				case <-stream.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return stream
+}
+
+func (mc multicall) chunkSize(total int) int {
+	size := mc.config.ChunkSize
+	if mc.config.Limits != nil {
+		size = mc.limits().ChunkSize
+	}
+	if size <= 0 || size > total {
+		size = total
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
@@ -0,0 +1,165 @@
+package multicall
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeETH answers aggregate eth_calls by passing every sub call to respond
+type fakeETH struct {
+	ethrpc.ETHInterface
+	mu          sync.Mutex
+	blockNumber int64
+	requests    int
+	params      []interface{}
+	respond     func(target [20]byte, callData []byte) AggregateReturn
+}
+
+func (f *fakeETH) SendRequest(result interface{}, method string, params ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	f.params = params
+	msg := params[0].(ethrpc.CallMsg)
+	input, err := hex.DecodeString(strings.TrimPrefix(msg.Data, AggregateMethod))
+	if err != nil {
+		return err
+	}
+	values, err := abi.Arguments{{Type: callListType}, {Type: boolType}}.Unpack(input)
+	if err != nil {
+		return err
+	}
+	calls := reflect.ValueOf(values[0])
+	returns := make([]AggregateReturn, calls.Len())
+	for i := range returns {
+		call := calls.Index(i)
+		var target [20]byte
+		reflect.Copy(reflect.ValueOf(target[:]), call.FieldByName("Target"))
+		returns[i] = f.respond(target, call.FieldByName("CallData").Bytes())
+	}
+
+	out, err := abi.Arguments{{Type: uint256Type}, {Type: returnListType}}.Pack(big.NewInt(f.blockNumber), returns)
+	if err != nil {
+		return err
+	}
+	*result.(*string) = "0x" + hex.EncodeToString(out)
+	return nil
+}
+
+// tryETH answers tryAggregate eth_calls of TryAggregator, which return no
+// block number, and the getBlockNumber() call appended to them
+type tryETH struct {
+	*fakeETH
+}
+
+func (f *tryETH) SendRequest(result interface{}, method string, params ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	f.params = params
+	msg := params[0].(ethrpc.CallMsg)
+	input, err := hex.DecodeString(strings.TrimPrefix(msg.Data, TryAggregateMethod))
+	if err != nil {
+		return err
+	}
+	values, err := abi.Arguments{{Type: boolType}, {Type: callListType}}.Unpack(input)
+	if err != nil {
+		return err
+	}
+	calls := reflect.ValueOf(values[1])
+	returns := make([]AggregateReturn, calls.Len())
+	for i := range returns {
+		call := calls.Index(i)
+		var target [20]byte
+		reflect.Copy(reflect.ValueOf(target[:]), call.FieldByName("Target"))
+		callData := call.FieldByName("CallData").Bytes()
+		if "0x"+hex.EncodeToString(callData) == GetBlockNumberMethod {
+			returns[i] = AggregateReturn{Success: true, Data: common.LeftPadBytes(big.NewInt(f.blockNumber).Bytes(), 32)}
+			continue
+		}
+		returns[i] = f.respond(target, callData)
+	}
+
+	out, err := abi.Arguments{{Type: returnListType}}.Pack(returns)
+	if err != nil {
+		return err
+	}
+	*result.(*string) = "0x" + hex.EncodeToString(out)
+	return nil
+}
+
+func echoArgument(target [20]byte, callData []byte) AggregateReturn {
+	return AggregateReturn{Success: true, Data: callData[4:]}
+}
+
+func numberedCalls(n int) ViewCalls {
+	calls := make(ViewCalls, n)
+	for i := range calls {
+		calls[i] = NewViewCall(big.NewInt(int64(i)).String(), "0x0000000000000000000000000000000000000001", "get(uint256)(uint256)", []interface{}{i})
+	}
+	return calls
+}
+
+func TestCallStream(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth, ChunkSize(3))
+	require.NoError(t, err)
+
+	calls := numberedCalls(10)
+	stream := mc.CallStream(calls, ethrpc.LatestBlock)
+	index := 0
+	for res := range stream.Results {
+		assert.Equal(t, calls[index].id, res.ID)
+		assert.Equal(t, uint64(42), res.BlockNumber)
+		assert.Equal(t, int64(index), res.Decoded[0].(*BigIntJSONString).ToBigInt().Int64())
+		index++
+	}
+	assert.NoError(t, <-stream.Errors)
+	assert.Equal(t, 10, index)
+	assert.Equal(t, 4, eth.requests)
+}
+
+func TestCallStreamTryAggregator(t *testing.T) {
+	eth := &tryETH{&fakeETH{blockNumber: 42, respond: echoArgument}}
+	mc, err := New(eth, ChunkSize(3), WithAggregator(TryAggregator(false)))
+	require.NoError(t, err)
+
+	calls := numberedCalls(7)
+	stream := mc.CallStream(calls, ethrpc.LatestBlock)
+	index := 0
+	for res := range stream.Results {
+		assert.Equal(t, calls[index].id, res.ID)
+		assert.Equal(t, uint64(42), res.BlockNumber)
+		assert.Equal(t, int64(index), res.Decoded[0].(*BigIntJSONString).ToBigInt().Int64())
+		index++
+	}
+	assert.NoError(t, <-stream.Errors)
+	assert.Equal(t, 7, index)
+	assert.Equal(t, 3, eth.requests)
+	// later chunks are pinned to the block getBlockNumber() returned
+	assert.Equal(t, ethrpc.BlockNumberRef(42), eth.params[1])
+}
+
+func TestCallStreamCancel(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	mc, err := New(eth, ChunkSize(1))
+	require.NoError(t, err)
+
+	stream := mc.CallStream(numberedCalls(10), ethrpc.LatestBlock)
+	<-stream.Results
+	stream.Cancel()
+	for range stream.Results {
+	}
+	assert.NoError(t, <-stream.Errors)
+	assert.Less(t, eth.requests, 10)
+}
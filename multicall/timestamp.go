@@ -0,0 +1,232 @@
+package multicall
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/howjmay/multicall/utils"
+)
+
+const (
+	// DefaultBlockTime is the average block time of Ethereum mainnet
+	DefaultBlockTime = 12 * time.Second
+
+	// timestampCacheSize is the number of header timestamps a BlockResolver
+	// keeps
+	timestampCacheSize = 4096
+)
+
+// BlockResolver finds the last block at or before a point in time by
+// searching block headers. The timestamps it reads are cached, up to
+// timestampCacheSize of them, and bound later searches, so resolving
+// nearby times gets cheaper over time.
+type BlockResolver struct {
+	eth       ethrpc.ETHInterface
+	blockTime time.Duration
+
+	mu sync.Mutex
+	// blocks is sorted by number, and so by timestamp
+	blocks []cachedBlock
+	reads  uint64
+}
+
+type cachedBlock struct {
+	number    uint64
+	timestamp uint64
+	// read orders the blocks by when they were last read, the least
+	// recently read one is evicted first
+	read uint64
+}
+
+// NewBlockResolver creates a resolver for the chain behind eth, using
+// blockTime, the chain's average block time, to guess the first block to
+// look at
+func NewBlockResolver(eth ethrpc.ETHInterface, blockTime time.Duration) *BlockResolver {
+	if blockTime <= 0 {
+		blockTime = DefaultBlockTime
+	}
+	return &BlockResolver{
+		eth:       eth,
+		blockTime: blockTime,
+	}
+}
+
+// Resolve returns the number and timestamp of the last block with a
+// timestamp at or before t. The search starts between the closest cached
+// blocks around t, falling back to the first and the latest block.
+func (r *BlockResolver) Resolve(t time.Time) (uint64, uint64, error) {
+	if t.Unix() < 0 {
+		return 0, 0, fmt.Errorf("invalid time %s", t)
+	}
+	target := uint64(t.Unix())
+
+	lo, hi, hasLo, hasHi := r.bounds(target)
+	if !hasHi {
+		// t may be after every cached block, only the latest block tells
+		latest, err := r.eth.GetBlockHeader(ethrpc.LatestBlock)
+		if err != nil {
+			return 0, 0, err
+		}
+		number, timestamp, err := r.parseHeader(latest)
+		if err != nil {
+			return 0, 0, err
+		}
+		if timestamp <= target {
+			return number, timestamp, nil
+		}
+		hi = cachedBlock{number: number, timestamp: timestamp}
+	}
+	if !hasLo {
+		timestamp, err := r.timestamp(0)
+		if err != nil {
+			return 0, 0, err
+		}
+		if timestamp > target {
+			return 0, 0, fmt.Errorf("%s is before the first block", t.UTC())
+		}
+		lo = cachedBlock{number: 0, timestamp: timestamp}
+	}
+
+	// lo.timestamp <= target < hi.timestamp holds throughout. The first
+	// guess steps back from hi by the average block time, then
+	// interpolation and bisection alternate so the search converges even
+	// when block times are irregular.
+	blocksBack := uint64(float64(hi.timestamp-target) / r.blockTime.Seconds())
+	guess := uint64(0)
+	if blocksBack < hi.number {
+		guess = hi.number - blocksBack
+	}
+	for step := 0; hi.number-lo.number > 1; step++ {
+		if step > 0 {
+			if step%2 == 1 {
+				guess = lo.number + (target-lo.timestamp)*(hi.number-lo.number)/(hi.timestamp-lo.timestamp)
+			} else {
+				guess = lo.number + (hi.number-lo.number)/2
+			}
+		}
+		if guess <= lo.number {
+			guess = lo.number + 1
+		}
+		if guess >= hi.number {
+			guess = hi.number - 1
+		}
+
+		timestamp, err := r.timestamp(guess)
+		if err != nil {
+			return 0, 0, err
+		}
+		if timestamp <= target {
+			lo = cachedBlock{number: guess, timestamp: timestamp}
+		} else {
+			hi = cachedBlock{number: guess, timestamp: timestamp}
+		}
+	}
+	return lo.number, lo.timestamp, nil
+}
+
+// bounds returns the last cached block at or before target and the first
+// one after it
+func (r *BlockResolver) bounds(target uint64) (lo, hi cachedBlock, hasLo, hasHi bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := sort.Search(len(r.blocks), func(i int) bool {
+		return r.blocks[i].timestamp > target
+	})
+	if i > 0 {
+		lo, hasLo = r.touch(i-1), true
+	}
+	if i < len(r.blocks) {
+		hi, hasHi = r.touch(i), true
+	}
+	return lo, hi, hasLo, hasHi
+}
+
+// touch marks the cached block at index i as read and returns it
+func (r *BlockResolver) touch(i int) cachedBlock {
+	r.reads++
+	r.blocks[i].read = r.reads
+	return r.blocks[i]
+}
+
+func (r *BlockResolver) timestamp(number uint64) (uint64, error) {
+	r.mu.Lock()
+	i := r.search(number)
+	if i < len(r.blocks) && r.blocks[i].number == number {
+		block := r.touch(i)
+		r.mu.Unlock()
+		return block.timestamp, nil
+	}
+	r.mu.Unlock()
+
+	header, err := r.eth.GetBlockHeader(ethrpc.BlockNumberRef(number))
+	if err != nil {
+		return 0, err
+	}
+	_, timestamp, err := r.parseHeader(header)
+	return timestamp, err
+}
+
+// search returns the index of the first cached block at or after number
+func (r *BlockResolver) search(number uint64) int {
+	return sort.Search(len(r.blocks), func(i int) bool {
+		return r.blocks[i].number >= number
+	})
+}
+
+// parseHeader returns the number and timestamp of header, caching them
+func (r *BlockResolver) parseHeader(header types.BlockHeader) (uint64, uint64, error) {
+	number, err := utils.HexToBigInt(header.Number)
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp, err := utils.HexToBigInt(header.Timestamp)
+	if err != nil {
+		return 0, 0, err
+	}
+	r.store(number.Uint64(), timestamp.Uint64())
+	return number.Uint64(), timestamp.Uint64(), nil
+}
+
+// store caches the timestamp of block number, evicting the least recently
+// read block when the cache is full
+func (r *BlockResolver) store(number, timestamp uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	block := cachedBlock{number: number, timestamp: timestamp, read: r.reads}
+	i := r.search(number)
+	if i < len(r.blocks) && r.blocks[i].number == number {
+		r.blocks[i] = block
+		return
+	}
+	r.blocks = append(r.blocks, cachedBlock{})
+	copy(r.blocks[i+1:], r.blocks[i:])
+	r.blocks[i] = block
+
+	if len(r.blocks) > timestampCacheSize {
+		oldest := 0
+		for j := range r.blocks {
+			if r.blocks[j].read < r.blocks[oldest].read {
+				oldest = j
+			}
+		}
+		r.blocks = append(r.blocks[:oldest], r.blocks[oldest+1:]...)
+	}
+}
+
+// CallAtTime runs calls at the last block with a timestamp at or before t
+func (mc multicall) CallAtTime(calls ViewCalls, t time.Time, opts ...CallOption) (*Result, error) {
+	number, timestamp, err := mc.resolver.Resolve(t)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mc.Call(calls, ethrpc.BlockNumberRef(number), opts...)
+	if result != nil {
+		result.BlockTimestamp = timestamp
+	}
+	return result, err
+}
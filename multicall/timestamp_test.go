@@ -0,0 +1,135 @@
+package multicall
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain serves block headers with the given timestamps, the last one
+// being the latest block
+type fakeChain struct {
+	*fakeETH
+	timestamps    []uint64
+	headerQueries int
+}
+
+func (f *fakeChain) GetBlockHeader(block ethrpc.BlockRef) (types.BlockHeader, error) {
+	f.headerQueries++
+	number, ok := block.Number()
+	if !ok {
+		number = uint64(len(f.timestamps) - 1)
+	}
+	if number >= uint64(len(f.timestamps)) {
+		return types.BlockHeader{}, fmt.Errorf("unknown block %d", number)
+	}
+	return types.BlockHeader{
+		Number:    fmt.Sprintf("0x%x", number),
+		Timestamp: fmt.Sprintf("0x%x", f.timestamps[number]),
+	}, nil
+}
+
+// irregularTimestamps returns n block timestamps with block times between
+// 1 and 30 seconds
+func irregularTimestamps(n int) []uint64 {
+	timestamps := make([]uint64, n)
+	timestamps[0] = 1600000000
+	for i := 1; i < n; i++ {
+		timestamps[i] = timestamps[i-1] + uint64(1+(i*7919)%30)
+	}
+	return timestamps
+}
+
+func TestBlockResolver(t *testing.T) {
+	chain := &fakeChain{timestamps: irregularTimestamps(5000)}
+	resolver := NewBlockResolver(chain, DefaultBlockTime)
+
+	for _, number := range []uint64{0, 1, 1234, 2500, 4998, 4999} {
+		timestamp := chain.timestamps[number]
+		got, gotTimestamp, err := resolver.Resolve(time.Unix(int64(timestamp), 0))
+		require.NoError(t, err)
+		assert.Equal(t, number, got)
+		assert.Equal(t, timestamp, gotTimestamp)
+
+		// a second before the next block still resolves to the same block
+		if number < 4999 {
+			got, _, err = resolver.Resolve(time.Unix(int64(chain.timestamps[number+1]-1), 0))
+			require.NoError(t, err)
+			assert.Equal(t, number, got)
+		}
+	}
+
+	got, _, err := resolver.Resolve(time.Unix(int64(chain.timestamps[4999]+3600), 0))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4999), got)
+
+	_, _, err = resolver.Resolve(time.Unix(int64(chain.timestamps[0]-1), 0))
+	assert.Error(t, err)
+}
+
+func TestBlockResolverCache(t *testing.T) {
+	chain := &fakeChain{timestamps: irregularTimestamps(100000)}
+	resolver := NewBlockResolver(chain, 15*time.Second)
+
+	at := time.Unix(int64(chain.timestamps[54321]), 0)
+	_, _, err := resolver.Resolve(at)
+	require.NoError(t, err)
+	assert.Less(t, chain.headerQueries, 60)
+
+	// the cached blocks around the time answer without any request
+	chain.headerQueries = 0
+	got, _, err := resolver.Resolve(at)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(54321), got)
+	assert.Equal(t, 0, chain.headerQueries)
+
+	// nearby times search between cached blocks, not from the first block
+	chain.headerQueries = 0
+	got, _, err = resolver.Resolve(time.Unix(int64(chain.timestamps[54400]), 0))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(54400), got)
+	assert.Less(t, chain.headerQueries, 20)
+
+	// times after every cached block still look at the latest block
+	chain.headerQueries = 0
+	got, _, err = resolver.Resolve(time.Unix(int64(chain.timestamps[99999]+60), 0))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(99999), got)
+	assert.Equal(t, 1, chain.headerQueries)
+}
+
+func TestBlockResolverCacheSize(t *testing.T) {
+	chain := &fakeChain{timestamps: irregularTimestamps(100000)}
+	resolver := NewBlockResolver(chain, 15*time.Second)
+
+	for number := 0; number < 100000; number += 7 {
+		got, _, err := resolver.Resolve(time.Unix(int64(chain.timestamps[number]), 0))
+		require.NoError(t, err)
+		require.Equal(t, uint64(number), got)
+	}
+	assert.LessOrEqual(t, len(resolver.blocks), timestampCacheSize)
+	for i := 1; i < len(resolver.blocks); i++ {
+		assert.Less(t, resolver.blocks[i-1].number, resolver.blocks[i].number)
+	}
+}
+
+func TestCallAtTime(t *testing.T) {
+	chain := &fakeChain{
+		fakeETH:    &fakeETH{blockNumber: 3, respond: echoArgument},
+		timestamps: []uint64{1000, 1012, 1024, 1036, 1048},
+	}
+	mc, err := New(chain)
+	require.NoError(t, err)
+
+	res, err := mc.CallAtTime(numberedCalls(2), time.Unix(1040, 0))
+	require.NoError(t, err)
+	assert.Equal(t, ethrpc.BlockNumberRef(3), chain.params[1])
+	assert.Equal(t, uint64(3), res.BlockNumber)
+	assert.Equal(t, uint64(1036), res.BlockTimestamp)
+	assert.Len(t, res.Calls, 2)
+}
@@ -21,3 +21,14 @@ func (bi BigIntJSONString) ToBigInt() *big.Int {
 	gobi := big.Int(bi)
 	return &gobi
 }
+
+// AsBigInt converts a decoded integer return value into a *big.Int
+func AsBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *BigIntJSONString:
+		return v.ToBigInt(), nil
+	case *big.Int:
+		return v, nil
+	}
+	return nil, fmt.Errorf("expected a big integer, got %T", value)
+}
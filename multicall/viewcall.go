@@ -6,11 +6,9 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
-	"regexp"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type ViewCall struct {
@@ -22,6 +20,10 @@ type ViewCall struct {
 
 type ViewCalls []ViewCall
 
+// NewViewCall creates a call of method on target. The method is either
+// written as "name(types)(types)", e.g. "balanceOf(address)(uint256)", or
+// as an ethers human-readable ABI fragment, e.g.
+// "function balanceOf(address owner) view returns (uint256)".
 func NewViewCall(id, target, method string, arguments []interface{}) ViewCall {
 	return ViewCall{
 		id:        id,
@@ -32,6 +34,10 @@ func NewViewCall(id, target, method string, arguments []interface{}) ViewCall {
 
 }
 
+func (call ViewCall) ID() string {
+	return call.id
+}
+
 func (call ViewCall) Validate() error {
 	if _, err := call.argsCallData(); err != nil {
 		return err
@@ -39,32 +45,32 @@ func (call ViewCall) Validate() error {
 	return nil
 }
 
-var patternInsideParenthesis = regexp.MustCompile(`\(.*?\)`)
-var patternNumericArg = regexp.MustCompile("u?int(256)|(8)")
+func (call ViewCall) signature() (*signature, error) {
+	compiled, err := compileMethod(call.method)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.sig, nil
+}
 
 func (call ViewCall) argumentTypes() []string {
-	rawArgs := patternInsideParenthesis.FindAllString(call.method, -1)[0]
-	rawArgs = strings.Replace(rawArgs, "(", "", -1)
-	rawArgs = strings.Replace(rawArgs, ")", "", -1)
-	if rawArgs == "" {
-		return []string{}
-	}
-	args := strings.Split(rawArgs, ",")
-	for index, arg := range args {
-		args[index] = strings.Trim(arg, " ")
+	compiled, err := compileMethod(call.method)
+	if err != nil {
+		return nil
 	}
-	return args
+	return append([]string(nil), compiled.argTypes...)
 }
 
 func (call ViewCall) returnTypes() []string {
-	rawArgs := patternInsideParenthesis.FindAllString(call.method, -1)[1]
-	rawArgs = strings.Replace(rawArgs, "(", "", -1)
-	rawArgs = strings.Replace(rawArgs, ")", "", -1)
-	args := strings.Split(rawArgs, ",")
-	for index, arg := range args {
-		args[index] = strings.Trim(arg, " ")
+	sig, err := call.signature()
+	if err != nil {
+		return nil
 	}
-	return args
+	returns := make([]string, len(sig.returns))
+	for index, ret := range sig.returns {
+		returns[index] = ret.canonicalType()
+	}
+	return returns
 }
 
 func (call ViewCall) callData() ([]byte, error) {
@@ -77,7 +83,7 @@ func (call ViewCall) callData() ([]byte, error) {
 		return nil, err
 	}
 
-	payload := make([]byte, 0)
+	payload := make([]byte, 0, len(methodPrefix)+len(argsSuffix))
 	payload = append(payload, methodPrefix...)
 	payload = append(payload, argsSuffix...)
 
@@ -85,207 +91,140 @@ func (call ViewCall) callData() ([]byte, error) {
 }
 
 func (call ViewCall) methodCallData() ([]byte, error) {
-	methodParts := strings.Split(call.method, ")(")
-	var method string
-	if len(methodParts) > 1 {
-		method = fmt.Sprintf("%s)", methodParts[0])
-	} else {
-		method = methodParts[0]
+	compiled, err := compileMethod(call.method)
+	if err != nil {
+		return nil, err
 	}
-	hash := crypto.Keccak256([]byte(method))
-	return hash[0:4], nil
+	return compiled.selector, nil
 }
 
 func (call ViewCall) argsCallData() ([]byte, error) {
-	argTypes := call.argumentTypes()
-	if len(argTypes) != len(call.arguments) {
+	compiled, err := compileMethod(call.method)
+	if err != nil {
+		return nil, err
+	}
+	if len(compiled.argTypes) != len(call.arguments) {
 		return nil, fmt.Errorf("number of argument types doesn't match with number of arguments for %s with method %s", call.id, call.method)
 	}
 	argumentValues := make([]interface{}, len(call.arguments))
-	arguments := make(abi.Arguments, len(call.arguments))
-
-	for index, argTypeStr := range argTypes {
-		argType, err := abi.NewType(argTypeStr, "", nil)
-		if err != nil {
-			return nil, err
-		}
-
-		arguments[index] = abi.Argument{Type: argType}
-		argumentValues[index], err = call.getArgument(index, argTypeStr)
+	for index, arg := range compiled.args {
+		argumentValues[index], err = call.getArgument(index, arg.Type)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return arguments.Pack(argumentValues...)
+	return compiled.args.Pack(argumentValues...)
 }
 
-func (call ViewCall) getArgument(index int, argumentType string) (interface{}, error) {
+func (call ViewCall) getArgument(index int, argumentType abi.Type) (interface{}, error) {
 	arg := call.arguments[index]
-	if argumentType == "address" {
+	switch argumentType.T {
+	case abi.AddressTy:
 		address, ok := arg.(string)
 		if !ok {
 			return nil, fmt.Errorf("expected address argument to be a string")
 		}
 		return toByteArray(address)
-	} else if patternNumericArg.MatchString(argumentType) {
-		if num, ok := arg.(json.Number); ok {
-			if v, err := num.Int64(); err != nil {
-				return big.NewInt(v), nil
-			} else if v, err := num.Float64(); err != nil {
-				return big.NewInt(int64(v)), nil
-			}
-		} else {
-			int64 := reflect.TypeOf(int64(0))
-			argType := reflect.TypeOf(arg)
-			kind := argType.Kind()
-			if kind == reflect.String {
-				if val, ok := new(big.Int).SetString(call.arguments[index].(string), 10); !ok {
-					return nil, fmt.Errorf("could not parse %s as a base 10 number", call.arguments[index])
-				} else {
-					return val, nil
-				}
-			} else if argType.ConvertibleTo(int64) {
-				return big.NewInt(reflect.ValueOf(arg).Convert(int64).Int()), nil
-			}
-		}
+	case abi.IntTy, abi.UintTy:
+		return integerArgument(arg, argumentType)
 	}
 	return arg, nil
 }
 
-func (call ViewCall) decode(raw []byte) ([]interface{}, error) {
-	retTypes := call.returnTypes()
-	args := make(abi.Arguments, 0)
-	for index, retTypeStr := range retTypes {
-		retType, err := abi.NewType(retTypeStr, "", nil)
-		if err != nil {
-			return nil, err
-		}
-		args = append(args, abi.Argument{Name: fmt.Sprintf("ret%d", index), Type: retType})
-	}
-	decoded := make(map[string]interface{})
-	err := args.UnpackIntoMap(decoded, raw)
-	if err != nil {
-		return nil, err
-	}
-	returns := make([]interface{}, len(retTypes))
-	for index := range retTypes {
-		key := fmt.Sprintf("ret%d", index)
-		item := decoded[key]
-		if bigint, ok := item.(*big.Int); ok {
-			returns[index] = (*BigIntJSONString)(bigint)
-		} else {
-			returns[index] = decoded[key]
-		}
-	}
-	return returns, nil
-}
-
-type callArgs struct {
-	Target   [20]byte
-	CallData []byte
-}
-
-func (calls ViewCalls) callData() ([]byte, error) {
-	payloadArgs := make([]callArgs, 0, len(calls))
-	for _, call := range calls {
-		callData, err := call.callData()
-		if err != nil {
-			return nil, err
+// integerArgument converts an integer given as a Go integer, *big.Int,
+// json.Number or base 10 string into the Go type go-ethereum packs typ
+// from: the sized integer type for 8, 16, 32 and 64 bits, *big.Int
+// otherwise
+func integerArgument(arg interface{}, typ abi.Type) (interface{}, error) {
+	var value *big.Int
+	switch v := arg.(type) {
+	case *big.Int:
+		value = v
+	case json.Number, string:
+		text := fmt.Sprint(v)
+		var ok bool
+		if value, ok = new(big.Int).SetString(text, 10); !ok {
+			return nil, fmt.Errorf("could not parse %s as a base 10 number", text)
 		}
-		targetBytes, err := toByteArray(call.target)
-		if err != nil {
-			return nil, err
+	default:
+		rv := reflect.ValueOf(arg)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value = big.NewInt(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = new(big.Int).SetUint64(rv.Uint())
+		default:
+			// left to abi.Arguments.Pack to reject
+			return arg, nil
 		}
-		payloadArgs = append(payloadArgs, callArgs{targetBytes, callData})
 	}
 
-	tupleArray, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
-		{Type: "address", Name: "Target"},
-		{Type: "bytes", Name: "CallData"},
-	})
-	if err != nil {
-		return nil, err
+	if !fitsInteger(value, typ) {
+		return nil, fmt.Errorf("%s does not fit %s", value, typ)
 	}
-	boolean, err := abi.NewType("bool", "", nil)
-	if err != nil {
-		return nil, err
+	goType := typ.GetType()
+	switch {
+	case goType.Kind() == reflect.Ptr:
+		return value, nil
+	case typ.T == abi.UintTy:
+		return reflect.ValueOf(value.Uint64()).Convert(goType).Interface(), nil
 	}
-	args := abi.Arguments{
-		{Type: tupleArray, Name: "calls"},
-		{Type: boolean, Name: "strict"},
-	}
-	return args.Pack(payloadArgs, false)
-}
-
-type retType struct {
-	Success bool
-	Data    []byte
-}
-
-type wrapperRet struct {
-	BlockNumber *big.Int
-	Returns     []retType
+	return reflect.ValueOf(value.Int64()).Convert(goType).Interface(), nil
 }
 
-func (calls ViewCalls) decodeWrapper(raw string) (*wrapperRet, error) {
-	rawBytes, err := hex.DecodeString(strings.Replace(raw, "0x", "", -1))
-	if err != nil {
-		return nil, err
+func fitsInteger(value *big.Int, typ abi.Type) bool {
+	if typ.T == abi.UintTy {
+		return value.Sign() >= 0 && value.BitLen() <= typ.Size
 	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(typ.Size-1))
+	return value.Cmp(limit) < 0 && value.Cmp(new(big.Int).Neg(limit)) >= 0
+}
 
-	uint256Type, err := abi.NewType("uint256", "", nil)
-	if err != nil {
-		return nil, err
-	}
-	returnType, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
-		{Name: "Success", Type: "bool"},
-		{Name: "Data", Type: "bytes"},
-	})
+// decode unpacks raw return data into the declared return values, in
+// order and keyed by name for the named ones
+func (call ViewCall) decode(raw []byte) ([]interface{}, map[string]interface{}, error) {
+	compiled, err := compileMethod(call.method)
 	if err != nil {
-		return nil, err
-	}
-	wrapperArgs := abi.Arguments{
-		{
-			Name: "BlockNumber",
-			Type: uint256Type,
-		},
-		{
-			Name: "Returns",
-			Type: returnType,
-		},
+		return nil, nil, err
 	}
-	data, err := wrapperArgs.Unpack(rawBytes)
+	values, err := compiled.returns.Unpack(raw)
 	if err != nil {
-		return nil, err
-	}
-	decoded := &wrapperRet{
-		BlockNumber: data[0].(*big.Int),
+		return nil, nil, err
 	}
-	returns := reflect.ValueOf(data[1])
-	for i := 0; i < returns.Len(); i++ {
-		elem := returns.Index(i)
-		ret := retType{
-			Success: elem.FieldByName("Success").Bool(),
-			Data:    elem.FieldByName("Data").Bytes(),
+	returns := make([]interface{}, len(values))
+	named := make(map[string]interface{})
+	for index, value := range values {
+		returns[index] = normalizeValue(value, compiled.returns[index].Type, compiled.sig.returns[index])
+		if name := compiled.sig.returns[index].name; name != "" {
+			named[name] = returns[index]
 		}
-		decoded.Returns = append(decoded.Returns, ret)
 	}
-	return decoded, err
+	return returns, named, nil
 }
 
-func (calls ViewCalls) decodeRaw(raw string) (*Result, error) {
-	decoded, err := calls.decodeWrapper(raw)
-	if err != nil {
-		return nil, err
+func (calls ViewCalls) aggregateCalls() ([]AggregateCall, error) {
+	aggregateCalls := make([]AggregateCall, 0, len(calls))
+	for _, call := range calls {
+		callData, err := call.callData()
+		if err != nil {
+			return nil, err
+		}
+		targetBytes, err := toByteArray(call.target)
+		if err != nil {
+			return nil, err
+		}
+		aggregateCalls = append(aggregateCalls, AggregateCall{Target: targetBytes, CallData: callData})
 	}
-	result := &Result{}
-	result.BlockNumber = decoded.BlockNumber.Uint64()
-	result.Calls = make(map[string]CallResult)
+	return aggregateCalls, nil
+}
 
+func (calls ViewCalls) decodeRaw(decoded *AggregateResult) (*Result, error) {
+	result := newResult(decoded)
 	for index, call := range calls {
 		callResult := CallResult{
 			Success: decoded.Returns[index].Success,
+			Status:  decoded.Returns[index].status(),
 			Raw:     decoded.Returns[index].Data,
 			Decoded: []interface{}{},
 		}
@@ -295,32 +234,47 @@ func (calls ViewCalls) decodeRaw(raw string) (*Result, error) {
 	return result, nil
 }
 
-func (calls ViewCalls) decode(raw string) (*Result, error) {
-	decoded, err := calls.decodeWrapper(raw)
-	if err != nil {
-		return nil, err
-	}
-	result := &Result{}
-	result.BlockNumber = decoded.BlockNumber.Uint64()
-	result.Calls = make(map[string]CallResult)
+// decode decodes the return values of every successful call. A call whose
+// return data does not match its return types gets a DecodeError and is
+// listed in the returned *DecodeErrors, the other calls are unaffected.
+func (calls ViewCalls) decode(decoded *AggregateResult) (*Result, error) {
+	result := newResult(decoded)
+	decodeErrors := make(map[string]error)
 	for index, call := range calls {
 		callResult := CallResult{
 			Success: decoded.Returns[index].Success,
+			Status:  decoded.Returns[index].status(),
 			Raw:     decoded.Returns[index].Data,
 		}
 		if decoded.Returns[index].Success {
-			returnValues, err := call.decode(decoded.Returns[index].Data)
+			returnValues, named, err := call.decode(decoded.Returns[index].Data)
 			if err != nil {
-				return nil, err
+				callResult.DecodeError = err
+				decodeErrors[call.id] = err
 			}
 			callResult.Decoded = returnValues
+			callResult.Named = named
 		}
 		result.Calls[call.id] = callResult
 	}
 
+	if len(decodeErrors) > 0 {
+		return result, &DecodeErrors{Errors: decodeErrors}
+	}
 	return result, nil
 }
 
+func newResult(decoded *AggregateResult) *Result {
+	result := &Result{
+		BlockHash: decoded.BlockHash,
+		Calls:     make(map[string]CallResult),
+	}
+	if decoded.BlockNumber != nil {
+		result.BlockNumber = decoded.BlockNumber.Uint64()
+	}
+	return result
+}
+
 func toByteArray(address string) ([20]byte, error) {
 	var addressBytes [20]byte
 	address = strings.Replace(address, "0x", "", -1)
@@ -1,8 +1,14 @@
 package multicall
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,8 +20,9 @@ func TestViewCall(t *testing.T) {
 		arguments: []interface{}{"0x1234", uint64(12)},
 	}
 	expectedArgTypes := []string{"address", "uint64"}
+	// the selector of the canonical balanceOf(address,uint64)
 	expectedCallData := []byte{
-		0x29, 0x5e, 0xaa, 0xdf, 0x0, 0x0, 0x0, 0x0,
+		0x80, 0x89, 0x45, 0x2e, 0x0, 0x0, 0x0, 0x0,
 		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 		0x12, 0x34, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
@@ -64,6 +71,25 @@ func TestEncodePatternNumericArgument(t *testing.T) {
 	assert.Equal(t, data1, data2)
 }
 
+func TestEncodeSizedIntegers(t *testing.T) {
+	expected, err := NewViewCall("key", "0x0", "get(uint8,int16,uint24)", []interface{}{uint8(7), int16(-2), big.NewInt(9)}).argsCallData()
+	assert.Nil(t, err)
+	for _, arguments := range [][]interface{}{
+		{7, -2, 9},
+		{big.NewInt(7), big.NewInt(-2), uint64(9)},
+		{"7", "-2", "9"},
+	} {
+		actual, err := NewViewCall("key", "0x0", "get(uint8,int16,uint24)", arguments).argsCallData()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err = NewViewCall("key", "0x0", "get(uint8)", []interface{}{256}).argsCallData()
+	assert.NotNil(t, err)
+	_, err = NewViewCall("key", "0x0", "get(uint256)", []interface{}{-1}).argsCallData()
+	assert.NotNil(t, err)
+}
+
 func TestEncodeBytes32Argument(t *testing.T) {
 	var bytes32Array = [32]uint8{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
 
@@ -77,3 +103,145 @@ func TestEncodeBytes32Argument(t *testing.T) {
 	_, err1 := vc1.argsCallData()
 	assert.Nil(t, err1)
 }
+
+func TestDecodeNamedReturns(t *testing.T) {
+	vc := NewViewCall("key", "0x0", "getReserves()(uint112 reserve0, uint112 reserve1, uint32 ts)", []interface{}{})
+	sig, err := vc.signature()
+	assert.Nil(t, err)
+	args, err := abiArguments(sig.returns)
+	assert.Nil(t, err)
+	raw, err := args.Pack(big.NewInt(10), big.NewInt(20), uint32(30))
+	assert.Nil(t, err)
+
+	decoded, named, err := vc.decode(raw)
+	assert.Nil(t, err)
+	assert.Len(t, decoded, 3)
+	assert.Equal(t, "10", named["reserve0"].(*BigIntJSONString).String())
+	assert.Equal(t, "20", named["reserve1"].(*BigIntJSONString).String())
+	assert.Equal(t, uint32(30), named["ts"])
+}
+
+func TestDecodeNestedReturns(t *testing.T) {
+	vc := NewViewCall("key", "0x0", "getPool(uint256)((address token, uint256 amount)[] legs, (uint256,bool))", []interface{}{1})
+	assert.Equal(t, []string{"(address,uint256)[]", "(uint256,bool)"}, vc.returnTypes())
+
+	sig, err := vc.signature()
+	assert.Nil(t, err)
+	args, err := abiArguments(sig.returns)
+	assert.Nil(t, err)
+	legs := []struct {
+		Token  common.Address
+		Amount *big.Int
+	}{{common.HexToAddress("0x01"), big.NewInt(5)}}
+	status := struct {
+		Field0 *big.Int
+		Field1 bool
+	}{big.NewInt(7), true}
+	raw, err := args.Pack(legs, status)
+	assert.Nil(t, err)
+
+	decoded, named, err := vc.decode(raw)
+	assert.Nil(t, err)
+	leg := named["legs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, common.HexToAddress("0x01"), leg["token"])
+	assert.Equal(t, "5", leg["amount"].(*BigIntJSONString).String())
+	assert.Equal(t, "7", decoded[1].(map[string]interface{})["0"].(*BigIntJSONString).String())
+	assert.Equal(t, true, decoded[1].(map[string]interface{})["1"])
+}
+
+func TestHumanReadableFragment(t *testing.T) {
+	legacy := NewViewCall("key", "0x0", "balanceOf(address)(uint256)", []interface{}{"0x1234"})
+	fragment := NewViewCall("key", "0x0", "function balanceOf(address owner) external view returns (uint)", []interface{}{"0x1234"})
+
+	legacyData, err := legacy.callData()
+	assert.Nil(t, err)
+	fragmentData, err := fragment.callData()
+	assert.Nil(t, err)
+	assert.Equal(t, legacyData, fragmentData)
+	assert.Equal(t, legacy.returnTypes(), fragment.returnTypes())
+
+	tuple := NewViewCall("key", "0x0", "function getLeg(uint256 index) view returns (tuple(address token, uint256[] amounts) leg)", []interface{}{1})
+	sig, err := tuple.signature()
+	assert.Nil(t, err)
+	assert.Equal(t, "getLeg(uint256)", sig.selectorText)
+	assert.Equal(t, []string{"(address,uint256[])"}, tuple.returnTypes())
+	assert.Equal(t, "leg", sig.returns[0].name)
+
+	invalid := NewViewCall("key", "0x0", "function balanceOf(address owner) viewable returns (uint256)", []interface{}{"0x1234"})
+	assert.NotNil(t, invalid.Validate())
+}
+
+func TestCanonicalSelector(t *testing.T) {
+	for method, selector := range map[string]string{
+		"balanceOf(address)(uint256)":       "70a08231",
+		"balanceOf(address owner)(uint256)": "70a08231",
+		"balanceOf( address )(uint256)":     "70a08231",
+		"foo(uint)(uint)":                   "2fbebd38",
+		"foo(uint256 memory x)":             "2fbebd38",
+	} {
+		data, err := NewViewCall("key", "0x0", method, []interface{}{"0x1234"}).methodCallData()
+		assert.Nil(t, err, method)
+		assert.Equal(t, selector, hex.EncodeToString(data), method)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	eth := &fakeETH{blockNumber: 42, respond: echoArgument}
+	calls := ViewCalls{
+		NewViewCall("ok", "0x0000000000000000000000000000000000000001", "get(uint256)(uint256)", []interface{}{1}),
+		NewViewCall("bad", "0x0000000000000000000000000000000000000001", "get(uint256)(string)", []interface{}{2}),
+	}
+
+	mc, err := New(eth)
+	assert.Nil(t, err)
+	res, err := mc.Call(calls, ethrpc.LatestBlock)
+	var decodeErrors *DecodeErrors
+	assert.ErrorAs(t, err, &decodeErrors)
+	assert.Len(t, decodeErrors.Errors, 1)
+	assert.Contains(t, decodeErrors.Errors, "bad")
+	assert.NotNil(t, res.Calls["bad"].DecodeError)
+	assert.Nil(t, res.Calls["ok"].DecodeError)
+	assert.Equal(t, "1", res.Calls["ok"].Decoded[0].(*BigIntJSONString).String())
+
+	out, err := json.Marshal(res.Calls["bad"])
+	assert.Nil(t, err)
+	var marshalled map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out, &marshalled))
+	assert.Equal(t, res.Calls["bad"].DecodeError.Error(), marshalled["DecodeError"])
+	assert.Equal(t, "success", marshalled["Status"])
+
+	out, err = json.Marshal(StreamResult{ID: "bad", BlockNumber: 42, CallResult: res.Calls["bad"]})
+	assert.Nil(t, err)
+	marshalled = nil
+	assert.Nil(t, json.Unmarshal(out, &marshalled))
+	assert.Equal(t, "bad", marshalled["ID"])
+	assert.Equal(t, float64(42), marshalled["BlockNumber"])
+	assert.Equal(t, res.Calls["bad"].DecodeError.Error(), marshalled["DecodeError"])
+
+	out, err = json.Marshal(res.Calls["ok"])
+	assert.Nil(t, err)
+	assert.NotContains(t, string(out), "DecodeError")
+
+	strict, err := New(eth, StrictDecoding())
+	assert.Nil(t, err)
+	res, err = strict.Call(calls, ethrpc.LatestBlock)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestCompiledMethodCache(t *testing.T) {
+	method := "transfer(address,uint256)(bool)"
+	first, err := compileMethod(method)
+	assert.Nil(t, err)
+	second, err := compileMethod(method)
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+
+	// appending to the cached selector must not change it
+	selector := append([]byte{}, first.selector...)
+	_ = append(first.selector, 0xff)
+	assert.Equal(t, selector, second.selector)
+
+	_, err = compileMethod("transfer(address")
+	assert.Error(t, err)
+}
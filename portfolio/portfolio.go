@@ -0,0 +1,244 @@
+package portfolio
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/multicall"
+	"github.com/howjmay/multicall/utils"
+)
+
+const (
+	// DefaultBatchSize is the maximum number of calls sent in one aggregate call
+	DefaultBatchSize = 500
+
+	balanceOfMethod = "balanceOf(address)(uint256)"
+	decimalsMethod  = "decimals()(uint8)"
+	symbolMethod    = "symbol()(string)"
+	nameMethod      = "name()(string)"
+)
+
+// Token holds the ERC20 metadata of a token. Metadata calls that revert or
+// return malformed data leave the corresponding field empty.
+type Token struct {
+	Address     string
+	Name        string
+	Symbol      string
+	Decimals    uint8
+	HasDecimals bool
+}
+
+// Balance is the balance of a holder for one token
+type Balance struct {
+	Holder  string
+	Token   string
+	Success bool
+	Raw     *big.Int
+	// Amount is Raw formatted with the token decimals, empty when the
+	// balanceOf or the decimals call failed
+	Amount string
+}
+
+// Portfolio is the result of reading balances for a set of holders and tokens
+type Portfolio struct {
+	BlockNumber uint64
+	// Tokens is keyed by the lower case token address
+	Tokens   map[string]*Token
+	Balances []Balance
+
+	// balances indexes Balances by balanceID
+	balances map[string]int
+}
+
+// Token returns the metadata of token, if it was read
+func (p *Portfolio) Token(token string) (*Token, bool) {
+	t, ok := p.Tokens[normalize(token)]
+	return t, ok
+}
+
+// Balance returns the balance of holder for token, if it was read
+func (p *Portfolio) Balance(holder, token string) (Balance, bool) {
+	i, ok := p.balances[balanceID(normalize(holder), normalize(token))]
+	if !ok {
+		return Balance{}, false
+	}
+	return p.Balances[i], true
+}
+
+type Option func(*Reader)
+
+// BatchSize sets the maximum number of calls in a single aggregate call
+func BatchSize(size int) Option {
+	return func(r *Reader) {
+		r.batchSize = size
+	}
+}
+
+// Reader reads ERC20 balances and metadata through a Multicall
+type Reader struct {
+	mc        multicall.Multicall
+	batchSize int
+}
+
+func New(mc multicall.Multicall, opts ...Option) *Reader {
+	r := &Reader{
+		mc:        mc,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Read fetches balanceOf for every holder and token pair, and decimals,
+// symbol and name for every token. All calls are packed into as few
+// aggregate calls as the batch size allows and every batch after the first
+// is pinned to the block the first one ran at.
+func (r *Reader) Read(holders, tokens []string, block ethrpc.BlockRef) (*Portfolio, error) {
+	holders, tokens = dedupe(holders), dedupe(tokens)
+
+	calls := make(multicall.ViewCalls, 0, len(tokens)*(3+len(holders)))
+	for _, token := range tokens {
+		calls = append(calls,
+			multicall.NewViewCall(metadataID("decimals", token), token, decimalsMethod, []interface{}{}),
+			multicall.NewViewCall(metadataID("symbol", token), token, symbolMethod, []interface{}{}),
+			multicall.NewViewCall(metadataID("name", token), token, nameMethod, []interface{}{}),
+		)
+		for _, holder := range holders {
+			calls = append(calls,
+				multicall.NewViewCall(balanceID(holder, token), token, balanceOfMethod, []interface{}{holder}),
+			)
+		}
+	}
+
+	raw, err := r.call(calls, block)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := &Portfolio{
+		BlockNumber: raw.BlockNumber,
+		Tokens:      make(map[string]*Token, len(tokens)),
+		Balances:    make([]Balance, 0, len(tokens)*len(holders)),
+		balances:    make(map[string]int, len(tokens)*len(holders)),
+	}
+	for _, token := range tokens {
+		t := &Token{Address: token}
+		if res, ok := raw.Calls[metadataID("decimals", token)]; ok && res.Success {
+			t.Decimals, t.HasDecimals = decodeDecimals(res.Raw)
+		}
+		if res, ok := raw.Calls[metadataID("symbol", token)]; ok && res.Success {
+			t.Symbol = decodeText(res.Raw)
+		}
+		if res, ok := raw.Calls[metadataID("name", token)]; ok && res.Success {
+			t.Name = decodeText(res.Raw)
+		}
+		portfolio.Tokens[token] = t
+
+		for _, holder := range holders {
+			balance := Balance{Holder: holder, Token: token}
+			if res, ok := raw.Calls[balanceID(holder, token)]; ok && res.Success && len(res.Raw) >= 32 {
+				balance.Success = true
+				balance.Raw = new(big.Int).SetBytes(res.Raw[:32])
+				if t.HasDecimals {
+					balance.Amount = utils.FormatUnits(balance.Raw, t.Decimals)
+				}
+			}
+			portfolio.balances[balanceID(holder, token)] = len(portfolio.Balances)
+			portfolio.Balances = append(portfolio.Balances, balance)
+		}
+	}
+	return portfolio, nil
+}
+
+func (r *Reader) call(calls multicall.ViewCalls, block ethrpc.BlockRef) (*multicall.Result, error) {
+	merged := &multicall.Result{Calls: make(map[string]multicall.CallResult, len(calls))}
+	size := r.batchSize
+	if size <= 0 {
+		size = len(calls)
+	}
+	for start := 0; start < len(calls); start += size {
+		end := start + size
+		if end > len(calls) {
+			end = len(calls)
+		}
+		res, err := r.mc.CallRaw(calls[start:end], block)
+		if err != nil {
+			return nil, err
+		}
+		if start == 0 {
+			merged.BlockNumber = res.BlockNumber
+			block = block.Pin(res.BlockNumber)
+		}
+		for id, callResult := range res.Calls {
+			merged.Calls[id] = callResult
+		}
+	}
+	return merged, nil
+}
+
+var stringType = mustNewType("string")
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// decodeText decodes a string return value. Tokens such as MKR return a
+// bytes32 instead, which is recognised by its fixed 32 byte length.
+func decodeText(raw []byte) string {
+	if len(raw) == 32 {
+		return string(bytes.TrimRight(raw, "\x00"))
+	}
+	values, err := abi.Arguments{{Type: stringType}}.Unpack(raw)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	text, _ := values[0].(string)
+	return text
+}
+
+func decodeDecimals(raw []byte) (uint8, bool) {
+	if len(raw) < 32 {
+		return 0, false
+	}
+	decimals := new(big.Int).SetBytes(raw[:32])
+	if !decimals.IsUint64() || decimals.Uint64() > 255 {
+		return 0, false
+	}
+	return uint8(decimals.Uint64()), true
+}
+
+func metadataID(field, token string) string {
+	return fmt.Sprintf("%s:%s", field, token)
+}
+
+func balanceID(holder, token string) string {
+	return fmt.Sprintf("balance:%s:%s", holder, token)
+}
+
+func normalize(address string) string {
+	return strings.ToLower(address)
+}
+
+func dedupe(addresses []string) []string {
+	seen := make(map[string]bool, len(addresses))
+	unique := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		address = normalize(address)
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		unique = append(unique, address)
+	}
+	return unique
+}
@@ -0,0 +1,89 @@
+package portfolio_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/howjmay/multicall/ethrpc"
+	"github.com/howjmay/multicall/multicall"
+	"github.com/howjmay/multicall/portfolio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	holder = "0x8134d518e0cef5388136c0de43d7e12278701ac5"
+	dai    = "0x6b175474e89094c44da98b954eedeac495271d0f"
+	mkr    = "0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2"
+	usdc   = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+)
+
+type fakeMulticall struct {
+	multicall.Multicall
+	responses map[string]multicall.CallResult
+	batches   int
+}
+
+func (f *fakeMulticall) CallRaw(calls multicall.ViewCalls, block ethrpc.BlockRef, opts ...multicall.CallOption) (*multicall.Result, error) {
+	f.batches++
+	res := &multicall.Result{BlockNumber: 100, Calls: make(map[string]multicall.CallResult)}
+	for id, callResult := range f.responses {
+		res.Calls[id] = callResult
+	}
+	return res, nil
+}
+
+func word(v int64) []byte {
+	return common.LeftPadBytes(big.NewInt(v).Bytes(), 32)
+}
+
+func TestRead(t *testing.T) {
+	symbol := make([]byte, 32)
+	copy(symbol, "MKR")
+	daiSymbol := append(append(word(32), word(3)...), common.RightPadBytes([]byte("DAI"), 32)...)
+	balance, _ := new(big.Int).SetString("1500000000000000000", 10)
+
+	mc := &fakeMulticall{responses: map[string]multicall.CallResult{
+		"decimals:" + dai:                {Success: true, Raw: word(18)},
+		"symbol:" + dai:                  {Success: true, Raw: daiSymbol},
+		"balance:" + holder + ":" + dai:  {Success: true, Raw: common.LeftPadBytes(balance.Bytes(), 32)},
+		"decimals:" + mkr:                {Success: true, Raw: word(18)},
+		"symbol:" + mkr:                  {Success: true, Raw: symbol},
+		"name:" + mkr:                    {Success: false},
+		"balance:" + holder + ":" + mkr:  {Success: false},
+		"decimals:" + usdc:               {Success: false},
+		"balance:" + holder + ":" + usdc: {Success: true, Raw: word(2500000)},
+	}}
+
+	p, err := portfolio.New(mc, portfolio.BatchSize(3)).Read([]string{holder, holder}, []string{dai, mkr, usdc}, ethrpc.LatestBlock)
+	require.NoError(t, err)
+	assert.Equal(t, 4, mc.batches)
+	assert.Equal(t, uint64(100), p.BlockNumber)
+
+	assert.Equal(t, "DAI", p.Tokens[dai].Symbol)
+	assert.Equal(t, "MKR", p.Tokens[mkr].Symbol)
+	assert.Equal(t, "", p.Tokens[mkr].Name)
+	assert.True(t, p.Tokens[mkr].HasDecimals)
+
+	daiBalance, ok := p.Balance(holder, dai)
+	require.True(t, ok)
+	assert.True(t, daiBalance.Success)
+	assert.Equal(t, "1.5", daiBalance.Amount)
+
+	mkrBalance, ok := p.Balance(holder, mkr)
+	require.True(t, ok)
+	assert.False(t, mkrBalance.Success)
+
+	// without decimals the raw balance cannot be formatted
+	usdcBalance, ok := p.Balance(holder, usdc)
+	require.True(t, ok)
+	assert.True(t, usdcBalance.Success)
+	assert.Equal(t, int64(2500000), usdcBalance.Raw.Int64())
+	assert.Empty(t, usdcBalance.Amount)
+	assert.False(t, p.Tokens[usdc].HasDecimals)
+
+	_, ok = p.Balance(mkr, dai)
+	assert.False(t, ok)
+	assert.Len(t, p.Balances, 3)
+}
@@ -0,0 +1,17 @@
+package types
+
+// CallFrame is a call as reported by geth's callTracer, with the calls it
+// made nested in Calls
+type CallFrame struct {
+	Type         string      `json:"type"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	Value        string      `json:"value"`
+	Gas          string      `json:"gas"`
+	GasUsed      string      `json:"gasUsed"`
+	Input        string      `json:"input"`
+	Output       string      `json:"output"`
+	Error        string      `json:"error"`
+	RevertReason string      `json:"revertReason"`
+	Calls        []CallFrame `json:"calls"`
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"math/big"
+	"strings"
+)
+
+// FormatUnits renders an integer amount of the smallest token unit as a
+// decimal string, e.g. 1500000000000000000 with 18 decimals becomes "1.5"
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	negative := amount.Sign() < 0
+	digits := new(big.Int).Abs(amount).String()
+	if decimals > 0 {
+		if len(digits) <= int(decimals) {
+			digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+		}
+		whole := digits[:len(digits)-int(decimals)]
+		fraction := strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+		digits = whole
+		if fraction != "" {
+			digits += "." + fraction
+		}
+	}
+	if negative {
+		return "-" + digits
+	}
+	return digits
+}